@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/go-chi/jwtauth"
 
@@ -10,8 +14,14 @@ import (
 	"github.com/go-chi/chi/middleware"
 	"github.com/sallescosta/user-and-products-manager/internal/entity"
 	"github.com/sallescosta/user-and-products-manager/internal/infra/database"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/database/migrations"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/jobs"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/mail"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/storage"
 	"github.com/sallescosta/user-and-products-manager/internal/infra/webserver/handlers"
-	"gorm.io/driver/sqlite"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/webserver/middlewares"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/webserver/problem"
+	"github.com/sallescosta/user-and-products-manager/pkg/signer"
 	"gorm.io/gorm"
 
 	"github.com/sallescosta/user-and-products-manager/configs"
@@ -40,50 +50,260 @@ import (
 func main() {
 	config, _ := configs.LoadConfig(".")
 
-	db, err := gorm.Open(sqlite.Open("test.db"), &gorm.Config{})
+	db, err := database.Open(database.Config{
+		Driver:          config.DBDriver,
+		Host:            config.DBHost,
+		Port:            config.DBPort,
+		User:            config.DBUser,
+		Password:        config.DBPassword,
+		Name:            config.DBName,
+		SSLMode:         config.DBSSLMode,
+		MaxOpenConns:    config.DBMaxOpenConns,
+		MaxIdleConns:    config.DBMaxIdleConns,
+		ConnMaxLifetime: time.Second * time.Duration(config.DBConnMaxLifetime),
+		ConnectRetries:  config.DBConnectRetries,
+		RetryInterval:   time.Second * time.Duration(config.DBConnectRetryInterval),
+	}, &gorm.Config{})
 	if err != nil {
 		panic(err)
 	}
 
-	db.AutoMigrate(&entity.Product{}, &entity.User{})
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(db, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if config.DBMigrateOnStart {
+		if _, err := migrations.Up(db); err != nil {
+			panic(err)
+		}
+	}
+
+	revokedTokenDB := database.NewRevokedToken(db)
+
+	categoryDB := database.NewCategory(db)
+
+	store, err := storage.NewLocal(config.StorageDir, config.StorageBaseURL)
+	if err != nil {
+		panic(err)
+	}
+
+	productDB := database.NewProduct(db)
+	imageDB := database.NewImage(db)
+	productHandler := handlers.NewProductHandler(productDB, categoryDB, store)
+	importHandler := handlers.NewImportHandler(productDB, config.ImportBatchSize, config.ImportMaxSize)
+	imageHandler := handlers.NewImageHandler(imageDB, store, config.ImageMaxSize, config.ThumbnailSize)
+	categoryHandler := handlers.NewCategoryHandler(categoryDB)
+	stockHandler := handlers.NewStockHandler(database.NewStock(db))
+	auditHandler := handlers.NewAuditHandler(database.NewAudit(db))
+	priceDB := database.NewPrice(db)
+	priceHandler := handlers.NewPriceHandler(priceDB)
+	variantHandler := handlers.NewVariantHandler(database.NewVariant(db))
+	userDB := database.NewUser(db)
+	if config.AdminEmail != "" {
+		if err := promoteAdmin(userDB, config.AdminEmail); err != nil {
+			panic(err)
+		}
+	}
+	passwordPolicy := entity.NewPasswordPolicy(config.PasswordMinLength)
+	if config.PasswordBreachedFile != "" {
+		file, err := os.Open(config.PasswordBreachedFile)
+		if err != nil {
+			panic(err)
+		}
+		err = passwordPolicy.LoadBreached(file)
+		file.Close()
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	var mailer mail.Mailer
+	switch config.MailDriver {
+	case "smtp":
+		mailer = mail.NewSMTP(config.SMTPHost, config.SMTPPort, config.SMTPUsername, config.SMTPPassword, config.MailFrom)
+	case "file":
+		if mailer, err = mail.NewFile(config.MailDir, config.MailFrom); err != nil {
+			panic(err)
+		}
+	case "log":
+		mailer = mail.NewLog(config.MailFrom)
+	default:
+		log.Fatalf("unknown MAIL_DRIVER %q", config.MailDriver)
+	}
+	loginThrottle := handlers.LoginThrottle{
+		Account: entity.LoginLimit{MaxFailures: config.LoginMaxFailures, Backoff: time.Second * time.Duration(config.LoginBackoff), Lockout: time.Second * time.Duration(config.LoginLockout)},
+		IP:      entity.LoginLimit{MaxFailures: config.LoginIPMaxFailures, Backoff: time.Second * time.Duration(config.LoginBackoff), Lockout: time.Second * time.Duration(config.LoginLockout)},
+	}
+	switch config.LoginThrottleStore {
+	case "memory":
+		loginThrottle.Store = database.NewMemoryLoginAttempt()
+	case "database":
+		loginThrottle.Store = database.NewLoginAttempt(db)
+	case "off":
+	default:
+		log.Fatalf("unknown LOGIN_THROTTLE_STORE %q", config.LoginThrottleStore)
+	}
+	userHandler := handlers.NewUserHandler(userDB, database.NewRefreshToken(db), revokedTokenDB, handlers.Verification{
+		Mailer:    mailer,
+		URL:       config.VerificationURL,
+		ExpiresIn: time.Second * time.Duration(config.VerificationExpiresIn),
+		Required:  config.VerificationRequired,
+	}, passwordPolicy, loginThrottle)
+	passwordHandler := handlers.NewPasswordHandler(userDB, database.NewPasswordReset(db), mailer, time.Second*time.Duration(config.PasswordResetExpiresIn), config.PasswordResetURL, passwordPolicy)
 
-	productHandler := handlers.NewProductHandler(database.NewProduct(db))
-	userHandler := handlers.NewUserHandler(database.NewUser(db))
+	if config.TrashRetention > 0 && config.TrashPurgeInterval > 0 {
+		retention := time.Second * time.Duration(config.TrashRetention)
+		go jobs.Every(context.Background(), "purge_trash", time.Second*time.Duration(config.TrashPurgeInterval), jobs.PurgeTrash(productDB, imageDB, store, retention))
+	}
+
+	if config.PriceSchedulerInterval > 0 {
+		go jobs.Every(context.Background(), "apply_scheduled_prices", time.Second*time.Duration(config.PriceSchedulerInterval), jobs.ApplyScheduledPrices(priceDB))
+	}
 
 	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.WithValue("jwt", config.TokenAuth))
 	r.Use(middleware.WithValue("JwtExpiresIn", config.JWTExpiresIn))
+	r.Use(middleware.WithValue("JwtRefreshExpiresIn", config.JWTRefreshExpiresIn))
+	r.Use(middleware.WithValue("PaginationMaxLimit", config.PaginationMaxLimit))
+	r.Use(middleware.WithValue("Signer", signer.New(config.SigningSecret)))
 
 	r.Use(LogRequest)
 
+	r.NotFound(problem.NotFound)
+	r.MethodNotAllowed(problem.MethodNotAllowed)
+
+	// image URLs are embedded in pages, which cannot send tokens
+	if mount := store.MountPath(); mount != "" {
+		r.Handle(mount+"/*", http.StripPrefix(mount, store))
+	} else {
+		log.Printf("STORAGE_BASE_URL has no path, uploaded files are left to its host to serve")
+	}
+
 	r.Route("/products", func(r chi.Router) {
 		r.Use(jwtauth.Verifier(config.TokenAuth))
-		r.Use(jwtauth.Authenticator)
+		r.Use(middlewares.Authenticator)
+		r.Use(middlewares.RejectRevoked(revokedTokenDB))
+		r.Use(middlewares.AuditActor)
 
 		r.Get("/", productHandler.GetProducts)
-		r.Post("/", productHandler.CreateProduct)
+		r.Get("/search", productHandler.SearchProducts)
+		r.Get("/export", productHandler.ExportProducts)
 		r.Get("/{id}", productHandler.GetProduct)
-		r.Put("/{id}", productHandler.UpdateProduct)
-		r.Delete("/{id}", productHandler.DeleteProduct)
+		r.Get("/{id}/stock", stockHandler.GetStock)
+		r.Get("/{id}/stock/movements", stockHandler.GetStockMovements)
+		r.Get("/{id}/history", auditHandler.GetProductHistory)
+		r.Get("/{id}/prices", priceHandler.GetPrices)
+		r.Get("/{id}/variants", variantHandler.GetVariants)
+		r.Get("/{id}/variants/{variantId}", variantHandler.GetVariant)
+		r.Get("/{id}/variants/{variantId}/stock/movements", stockHandler.GetVariantStockMovements)
+		r.Get("/{id}/images", imageHandler.GetImages)
+
+		r.Group(func(r chi.Router) {
+			r.Use(middlewares.RequireRole(entity.RoleEditor))
+
+			r.Post("/", productHandler.CreateProduct)
+			r.Post("/import", importHandler.ImportProducts)
+			r.Put("/{id}", productHandler.UpdateProduct)
+			r.Patch("/{id}", productHandler.PatchProduct)
+			r.Delete("/{id}", productHandler.DeleteProduct)
+			r.Get("/trash", productHandler.GetTrash)
+			r.Post("/{id}/restore", productHandler.RestoreProduct)
+			r.Put("/{id}/categories", productHandler.SetProductCategories)
+			r.Post("/{id}/stock/movements", stockHandler.CreateStockMovement)
+			r.Post("/{id}/prices", priceHandler.SchedulePrice)
+			r.Delete("/{id}/prices/{priceId}", priceHandler.CancelPrice)
+			r.Post("/{id}/variants", variantHandler.CreateVariant)
+			r.Put("/{id}/variants/{variantId}", variantHandler.UpdateVariant)
+			r.Delete("/{id}/variants/{variantId}", variantHandler.DeleteVariant)
+			r.Post("/{id}/images", imageHandler.UploadImage)
+			r.Put("/{id}/images/order", imageHandler.ReorderImages)
+			r.Delete("/{id}/images/{imageId}", imageHandler.DeleteImage)
+		})
 	})
 
-	r.Post("/users", userHandler.CreateUser)
-	r.Post("/users/generate_token", userHandler.GetJWT)
-	r.Get("/users", userHandler.AllUsers)
+	r.Route("/categories", func(r chi.Router) {
+		r.Use(jwtauth.Verifier(config.TokenAuth))
+		r.Use(middlewares.Authenticator)
+		r.Use(middlewares.RejectRevoked(revokedTokenDB))
+		r.Use(middlewares.AuditActor)
+
+		r.Get("/", categoryHandler.GetCategories)
+		r.Get("/{id}", categoryHandler.GetCategory)
 
-	r.Route("/users", func(r chi.Router) {
+		r.Group(func(r chi.Router) {
+			r.Use(middlewares.RequireRole(entity.RoleEditor))
+
+			r.Post("/", categoryHandler.CreateCategory)
+			r.Put("/{id}", categoryHandler.UpdateCategory)
+			r.Delete("/{id}", categoryHandler.DeleteCategory)
+		})
+	})
+
+	r.Route("/audit", func(r chi.Router) {
 		r.Use(jwtauth.Verifier(config.TokenAuth))
-		r.Use(jwtauth.Authenticator)
+		r.Use(middlewares.Authenticator)
+		r.Use(middlewares.RejectRevoked(revokedTokenDB))
+		r.Use(middlewares.RequireRole(entity.RoleAdmin))
+
+		r.Get("/", auditHandler.GetAudit)
 	})
 
-	http.HandleFunc("/products", productHandler.CreateProduct)
+	r.Route("/users", func(r chi.Router) {
+		r.Post("/", userHandler.CreateUser)
+		r.Post("/generate_token", userHandler.GetJWT)
+		r.Post("/refresh_token", userHandler.RefreshToken)
+		r.Post("/password/forgot", passwordHandler.ForgotPassword)
+		r.Post("/password/reset", passwordHandler.ResetPassword)
+		r.Get("/verify", userHandler.VerifyEmail)
+
+		r.Group(func(r chi.Router) {
+			r.Use(jwtauth.Verifier(config.TokenAuth))
+			r.Use(middlewares.Authenticator)
+			r.Use(middlewares.RejectRevoked(revokedTokenDB))
+
+			r.Post("/logout", userHandler.Logout)
+
+			r.Group(func(r chi.Router) {
+				r.Use(middlewares.RequireRole(entity.RoleAdmin))
+
+				r.Get("/", userHandler.AllUsers)
+				r.Put("/{id}/role", userHandler.UpdateUserRole)
+				r.Post("/{id}/unlock", userHandler.UnlockUser)
+			})
+		})
+	})
 
 	r.Get("/docs/*", httpSwagger.Handler(httpSwagger.URL("http://localhost:8000/docs/doc.json")))
 	log.Fatal(http.ListenAndServe(":8000", r))
 }
 
+// promoteAdmin makes the user of email an admin, for deployments whose users
+// all predate roles and were made viewers. A missing user is only logged, as
+// they may not have signed up yet.
+func promoteAdmin(userDB database.UserInterface, email string) error {
+	user, err := userDB.FindByEmail(email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("no user has the ADMIN_EMAIL address yet")
+		return nil
+	}
+	if err != nil || user.Role == entity.RoleAdmin {
+		return err
+	}
+
+	user.Role = entity.RoleAdmin
+	if err = userDB.Update(user); err != nil {
+		return err
+	}
+	log.Printf("user %s promoted to admin through ADMIN_EMAIL", user.ID)
+	return nil
+}
+
 func LogRequest(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		log.Printf("Request: %s %s", r.Method, r.URL.Path)
@@ -6,15 +6,55 @@ import (
 )
 
 type conf struct {
-	DBDriver      string           `mapstructure:"DB_DRIVER"`
-	DBHost        string           `mapstructure:"DB_HOST"`
-	DBPort        string           `mapstructure:"DB_PORT"`
-	DBPassword    string           `mapstructure:"DB_PASSWORD"`
-	DBName        string           `mapstructure:"DB_NAME"`
-	WebServerPort string           `mapstructure:"WEB_SERVER_PORT"`
-	JWTSecret     string           `mapstructure:"JWT_SECRET"`
-	JWTExpiresIn  int              `mapstructure:"JWT_EXPIRES_IN"`
-	TokenAuth     *jwtauth.JWTAuth `mapstructure:"TOKEN_AUTH"`
+	DBDriver               string           `mapstructure:"DB_DRIVER"`
+	DBHost                 string           `mapstructure:"DB_HOST"`
+	DBPort                 string           `mapstructure:"DB_PORT"`
+	DBUser                 string           `mapstructure:"DB_USER"`
+	DBPassword             string           `mapstructure:"DB_PASSWORD"`
+	DBName                 string           `mapstructure:"DB_NAME"`
+	DBSSLMode              string           `mapstructure:"DB_SSL_MODE"`
+	DBMaxOpenConns         int              `mapstructure:"DB_MAX_OPEN_CONNS"`
+	DBMaxIdleConns         int              `mapstructure:"DB_MAX_IDLE_CONNS"`
+	DBConnMaxLifetime      int              `mapstructure:"DB_CONN_MAX_LIFETIME"`
+	DBConnectRetries       int              `mapstructure:"DB_CONNECT_RETRIES"`
+	DBConnectRetryInterval int              `mapstructure:"DB_CONNECT_RETRY_INTERVAL"`
+	DBMigrateOnStart       bool             `mapstructure:"DB_MIGRATE_ON_START"`
+	WebServerPort          string           `mapstructure:"WEB_SERVER_PORT"`
+	JWTSecret              string           `mapstructure:"JWT_SECRET"`
+	JWTExpiresIn           int              `mapstructure:"JWT_EXPIRES_IN"`
+	JWTRefreshExpiresIn    int              `mapstructure:"JWT_REFRESH_EXPIRES_IN"`
+	PaginationMaxLimit     int              `mapstructure:"PAGINATION_MAX_LIMIT"`
+	SigningSecret          string           `mapstructure:"SIGNING_SECRET"`
+	TrashRetention         int              `mapstructure:"TRASH_RETENTION"`
+	TrashPurgeInterval     int              `mapstructure:"TRASH_PURGE_INTERVAL"`
+	PriceSchedulerInterval int              `mapstructure:"PRICE_SCHEDULER_INTERVAL"`
+	StorageDir             string           `mapstructure:"STORAGE_DIR"`
+	StorageBaseURL         string           `mapstructure:"STORAGE_BASE_URL"`
+	ImageMaxSize           int64            `mapstructure:"IMAGE_MAX_SIZE"`
+	ThumbnailSize          int              `mapstructure:"THUMBNAIL_SIZE"`
+	ImportBatchSize        int              `mapstructure:"IMPORT_BATCH_SIZE"`
+	ImportMaxSize          int64            `mapstructure:"IMPORT_MAX_SIZE"`
+	MailDriver             string           `mapstructure:"MAIL_DRIVER"`
+	MailFrom               string           `mapstructure:"MAIL_FROM"`
+	MailDir                string           `mapstructure:"MAIL_DIR"`
+	SMTPHost               string           `mapstructure:"SMTP_HOST"`
+	SMTPPort               int              `mapstructure:"SMTP_PORT"`
+	SMTPUsername           string           `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword           string           `mapstructure:"SMTP_PASSWORD"`
+	PasswordResetExpiresIn int              `mapstructure:"PASSWORD_RESET_EXPIRES_IN"`
+	PasswordResetURL       string           `mapstructure:"PASSWORD_RESET_URL"`
+	VerificationRequired   bool             `mapstructure:"EMAIL_VERIFICATION_REQUIRED"`
+	VerificationExpiresIn  int              `mapstructure:"EMAIL_VERIFICATION_EXPIRES_IN"`
+	VerificationURL        string           `mapstructure:"EMAIL_VERIFICATION_URL"`
+	PasswordMinLength      int              `mapstructure:"PASSWORD_MIN_LENGTH"`
+	PasswordBreachedFile   string           `mapstructure:"PASSWORD_BREACHED_FILE"`
+	LoginThrottleStore     string           `mapstructure:"LOGIN_THROTTLE_STORE"`
+	LoginMaxFailures       int              `mapstructure:"LOGIN_MAX_FAILURES"`
+	LoginIPMaxFailures     int              `mapstructure:"LOGIN_IP_MAX_FAILURES"`
+	LoginBackoff           int              `mapstructure:"LOGIN_BACKOFF"`
+	LoginLockout           int              `mapstructure:"LOGIN_LOCKOUT"`
+	AdminEmail             string           `mapstructure:"ADMIN_EMAIL"`
+	TokenAuth              *jwtauth.JWTAuth `mapstructure:"TOKEN_AUTH"`
 }
 
 func LoadConfig(path string) (*conf, error) {
@@ -25,6 +65,38 @@ func LoadConfig(path string) (*conf, error) {
 	viper.AddConfigPath(path)
 	viper.SetConfigFile(".env")
 	viper.AutomaticEnv()
+	viper.SetDefault("DB_DRIVER", "sqlite")
+	viper.SetDefault("DB_MAX_OPEN_CONNS", 10)
+	viper.SetDefault("DB_MAX_IDLE_CONNS", 5)
+	viper.SetDefault("DB_CONN_MAX_LIFETIME", 60*30)
+	viper.SetDefault("DB_CONNECT_RETRIES", 5)
+	viper.SetDefault("DB_CONNECT_RETRY_INTERVAL", 1)
+	viper.SetDefault("DB_MIGRATE_ON_START", true)
+	viper.SetDefault("JWT_REFRESH_EXPIRES_IN", 60*60*24*30)
+	viper.SetDefault("PAGINATION_MAX_LIMIT", 100)
+	viper.SetDefault("TRASH_RETENTION", 60*60*24*30)
+	viper.SetDefault("TRASH_PURGE_INTERVAL", 60*60)
+	viper.SetDefault("PRICE_SCHEDULER_INTERVAL", 60)
+	viper.SetDefault("STORAGE_DIR", "uploads")
+	viper.SetDefault("STORAGE_BASE_URL", "/media")
+	viper.SetDefault("IMAGE_MAX_SIZE", 5<<20)
+	viper.SetDefault("THUMBNAIL_SIZE", 256)
+	viper.SetDefault("IMPORT_BATCH_SIZE", 500)
+	viper.SetDefault("IMPORT_MAX_SIZE", 10<<20)
+	viper.SetDefault("MAIL_DRIVER", "file")
+	viper.SetDefault("MAIL_FROM", "no-reply@localhost")
+	viper.SetDefault("MAIL_DIR", "mail")
+	viper.SetDefault("SMTP_PORT", 587)
+	viper.SetDefault("PASSWORD_RESET_EXPIRES_IN", 60*60)
+	viper.SetDefault("EMAIL_VERIFICATION_REQUIRED", false)
+	viper.SetDefault("EMAIL_VERIFICATION_EXPIRES_IN", 60*60*24*3)
+	viper.SetDefault("EMAIL_VERIFICATION_URL", "http://localhost:8000/users/verify")
+	viper.SetDefault("PASSWORD_MIN_LENGTH", 8)
+	viper.SetDefault("LOGIN_THROTTLE_STORE", "memory")
+	viper.SetDefault("LOGIN_MAX_FAILURES", 5)
+	viper.SetDefault("LOGIN_IP_MAX_FAILURES", 50)
+	viper.SetDefault("LOGIN_BACKOFF", 1)
+	viper.SetDefault("LOGIN_LOCKOUT", 60*15)
 
 	err := viper.ReadInConfig()
 	if err != nil {
@@ -37,5 +109,8 @@ func LoadConfig(path string) (*conf, error) {
 	}
 
 	cfg.TokenAuth = jwtauth.New("HS256", []byte(cfg.JWTSecret), nil)
+	if cfg.SigningSecret == "" {
+		cfg.SigningSecret = cfg.JWTSecret
+	}
 	return cfg, nil
 }
@@ -1,8 +1,93 @@
 package dto
 
+import (
+	"encoding/json"
+	"time"
+
+	entityPkg "github.com/sallescosta/user-and-products-manager/pkg/entity"
+)
+
+// CreateProductInput takes the price as an exact decimal, either a JSON number
+// or a string, in the currency given (USD when omitted).
 type CreateProductInput struct {
-	Name  string  `json:"name"`
-	Price float64 `json:"price"`
+	Name     string      `json:"name"`
+	Price    json.Number `json:"price" swaggertype:"string" example:"12.34"`
+	Currency string      `json:"currency" example:"USD"`
+}
+
+// SchedulePriceInput takes the price like CreateProductInput, and the moment
+// it takes effect.
+type SchedulePriceInput struct {
+	Price         json.Number `json:"price" swaggertype:"string" example:"9.99"`
+	Currency      string      `json:"currency" example:"USD"`
+	EffectiveFrom time.Time   `json:"effective_from" example:"2030-01-01T00:00:00Z"`
+}
+
+// VariantInput describes a variant of a product. Without a price the variant
+// is sold at the price of the product; with one, it must be in the currency of
+// the product.
+type VariantInput struct {
+	SKU        string            `json:"sku" example:"TEE-RED-M"`
+	Attributes map[string]string `json:"attributes"`
+	Price      json.Number       `json:"price,omitempty" swaggertype:"string" example:"15.99"`
+	Currency   string            `json:"currency,omitempty" example:"USD"`
+	Quantity   int               `json:"quantity" example:"10"`
+}
+
+// ReorderImagesInput lists the IDs of every image of a product, in their new
+// order.
+type ReorderImagesInput struct {
+	IDs []string `json:"ids"`
+}
+
+type ImportStatus string
+
+const (
+	// ImportCreated rows were saved as new products.
+	ImportCreated ImportStatus = "created"
+	// ImportValid rows passed validation in a dry run.
+	ImportValid ImportStatus = "valid"
+	// ImportFailed rows were not saved, as told by their code and error.
+	ImportFailed ImportStatus = "failed"
+)
+
+// ImportRow reports on a row of an import, by the line it starts at. ID is
+// the product the row was (or, in a dry run, would be) saved as.
+type ImportRow struct {
+	Line   int          `json:"line"`
+	Status ImportStatus `json:"status"`
+	ID     string       `json:"id,omitempty"`
+	Code   string       `json:"code,omitempty"`
+	Error  string       `json:"error,omitempty"`
+}
+
+type ImportReport struct {
+	DryRun  bool        `json:"dry_run"`
+	Total   int         `json:"total"`
+	Created int         `json:"created"`
+	Valid   int         `json:"valid"`
+	Failed  int         `json:"failed"`
+	Rows    []ImportRow `json:"rows"`
+}
+
+// ProductExport is a product as exported, with its price as a decimal like
+// CreateProductInput takes it, so that exports can be imported again.
+type ProductExport struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Price     string    `json:"price"`
+	Currency  string    `json:"currency"`
+	Quantity  int       `json:"quantity"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ProductDocument is the editable part of a product, the document PATCH
+// requests apply their changes to. The price amount is in minor units, as
+// products return it.
+type ProductDocument struct {
+	Name  string          `json:"name"`
+	Price entityPkg.Money `json:"price"`
 }
 
 type CreateUserInput struct {
@@ -17,10 +102,54 @@ type GetJWTInput struct {
 }
 
 type GetJWTOutput struct {
-	AccessToken string `json:"access_token"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type RefreshTokenInput struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type VerifyEmailOutput struct {
+	Email    string `json:"email"`
+	Verified bool   `json:"verified"`
+}
+
+type ForgotPasswordInput struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordInput struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
 }
 
 type GetUsersOutput struct {
 	Name  string `json:"name"`
 	Email string `json:"email"`
 }
+
+type UpdateUserRoleInput struct {
+	Role string `json:"role"`
+}
+
+type CreateCategoryInput struct {
+	Name     string  `json:"name"`
+	ParentID *string `json:"parent_id"`
+}
+
+type SetProductCategoriesInput struct {
+	CategoryIDs []string `json:"category_ids"`
+}
+
+type CreateStockMovementInput struct {
+	Type          string `json:"type"`
+	Quantity      int    `json:"quantity"`
+	Reason        string `json:"reason"`
+	AllowNegative bool   `json:"allow_negative"`
+}
+
+type GetStockOutput struct {
+	ProductID string `json:"product_id"`
+	Quantity  int    `json:"quantity"`
+}
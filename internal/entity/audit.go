@@ -0,0 +1,131 @@
+package entity
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sallescosta/user-and-products-manager/pkg/entity"
+)
+
+var ErrInvalidAuditAction = errors.New("invalid audit action")
+
+type AuditAction string
+
+const (
+	AuditCreate  AuditAction = "create"
+	AuditUpdate  AuditAction = "update"
+	AuditDelete  AuditAction = "delete"
+	AuditRestore AuditAction = "restore"
+	// AuditPurge is the permanent deletion of a product from the trash.
+	AuditPurge AuditAction = "purge"
+)
+
+// ParseAuditAction validates an action name.
+func ParseAuditAction(s string) (AuditAction, error) {
+	switch action := AuditAction(s); action {
+	case AuditCreate, AuditUpdate, AuditDelete, AuditRestore, AuditPurge:
+		return action, nil
+	}
+	return "", ErrInvalidAuditAction
+}
+
+// AuditChange is the value of a field before and after a change. From is nil
+// for created fields and To for deleted ones.
+type AuditChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// AuditChanges maps the changed fields to their change. It is stored as JSON.
+type AuditChanges map[string]AuditChange
+
+func (c AuditChanges) Value() (driver.Value, error) {
+	if c == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(c)
+	return string(b), err
+}
+
+func (c *AuditChanges) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*c = AuditChanges{}
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	case []byte:
+		return json.Unmarshal(v, c)
+	}
+	return fmt.Errorf("cannot scan %T into AuditChanges", value)
+}
+
+// AuditEntry records one change to an entity: who made it, in which request,
+// and how its fields changed. Entries are never updated nor deleted, and
+// outlive the entity they describe. ActorID is empty for changes made by the
+// server itself, such as purges. ProductID is the product the entity is or
+// belongs to, so that the history of a product includes its variants and
+// images.
+type AuditEntry struct {
+	ID         entity.ID    `json:"id"`
+	EntityType string       `json:"entity_type" gorm:"size:32;index:idx_audit_entity"`
+	EntityID   entity.ID    `json:"entity_id" gorm:"index:idx_audit_entity"`
+	ProductID  *entity.ID   `json:"product_id,omitempty" gorm:"index"`
+	Action     AuditAction  `json:"action" gorm:"size:16"`
+	ActorID    string       `json:"actor_id" gorm:"size:36;index"`
+	RequestID  string       `json:"request_id" gorm:"size:64"`
+	Changes    AuditChanges `json:"changes" gorm:"type:text" swaggertype:"object"`
+	CreatedAt  time.Time    `json:"created_at" gorm:"index"`
+}
+
+// NewAuditEntry records the change of an entity from before to after, the
+// audited fields of each state. before is nil for a creation and after for a
+// deletion.
+func NewAuditEntry(entityType string, entityID entity.ID, action AuditAction, before, after map[string]interface{}) *AuditEntry {
+	return &AuditEntry{
+		ID:         entity.NewID(),
+		EntityType: entityType,
+		EntityID:   entityID,
+		Action:     action,
+		Changes:    Diff(before, after),
+		CreatedAt:  time.Now(),
+	}
+}
+
+// Diff lists the fields whose value differs between before and after, as
+// they would be encoded in JSON.
+func Diff(before, after map[string]interface{}) AuditChanges {
+	changes := AuditChanges{}
+	for field, from := range before {
+		to, ok := after[field]
+		if !ok || !sameJSON(from, to) {
+			changes[field] = AuditChange{From: from, To: to}
+		}
+	}
+	for field, to := range after {
+		if _, ok := before[field]; !ok {
+			changes[field] = AuditChange{To: to}
+		}
+	}
+	return changes
+}
+
+func sameJSON(a, b interface{}) bool {
+	x, errA := json.Marshal(a)
+	y, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(x, y)
+}
+
+// AuditFields are the fields of the product tracked by its create, update and
+// delete entries. Category assignments are recorded as changes of
+// "categories", and the quantity has its own history in the stock ledger.
+func (p *Product) AuditFields() map[string]interface{} {
+	return map[string]interface{}{
+		"name":  p.Name,
+		"price": p.Price,
+	}
+}
@@ -0,0 +1,57 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/sallescosta/user-and-products-manager/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAuditEntry(t *testing.T) {
+	product, err := NewProduct("Mouse", usd(1000))
+	assert.Nil(t, err)
+
+	created := NewAuditEntry("product", product.ID, AuditCreate, nil, product.AuditFields())
+	assert.NotEmpty(t, created.ID)
+	assert.Equal(t, product.ID, created.EntityID)
+	assert.Equal(t, AuditChanges{
+		"name":  {To: "Mouse"},
+		"price": {To: usd(1000)},
+	}, created.Changes)
+
+	before := product.AuditFields()
+	product.Price = usd(1299)
+	updated := NewAuditEntry("product", product.ID, AuditUpdate, before, product.AuditFields())
+	assert.Equal(t, AuditChanges{"price": {From: usd(1000), To: usd(1299)}}, updated.Changes)
+
+	deleted := NewAuditEntry("product", product.ID, AuditDelete, product.AuditFields(), nil)
+	assert.Equal(t, AuditChanges{
+		"name":  {From: "Mouse"},
+		"price": {From: usd(1299)},
+	}, deleted.Changes)
+
+	restored := NewAuditEntry("product", product.ID, AuditRestore, nil, nil)
+	assert.Empty(t, restored.Changes)
+}
+
+func TestAuditChanges_Scan(t *testing.T) {
+	changes := AuditChanges{"price": {From: entity.Money{Amount: 1000, Currency: "USD"}, To: entity.Money{Amount: 1299, Currency: "USD"}}}
+	value, err := changes.Value()
+	assert.Nil(t, err)
+
+	var scanned AuditChanges
+	assert.Nil(t, scanned.Scan(value))
+	assert.Equal(t, map[string]interface{}{"amount": float64(1299), "currency": "USD"}, scanned["price"].To)
+
+	assert.Nil(t, scanned.Scan(nil))
+	assert.Empty(t, scanned)
+}
+
+func TestParseAuditAction(t *testing.T) {
+	action, err := ParseAuditAction("purge")
+	assert.Nil(t, err)
+	assert.Equal(t, AuditPurge, action)
+
+	_, err = ParseAuditAction("rename")
+	assert.ErrorIs(t, err, ErrInvalidAuditAction)
+}
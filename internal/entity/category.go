@@ -0,0 +1,92 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/sallescosta/user-and-products-manager/pkg/entity"
+)
+
+var (
+	ErrCategoryCycle          = errors.New("category cannot be moved under itself or one of its descendants")
+	ErrCategoryHasChildren    = errors.New("category has subcategories")
+	ErrParentCategoryNotFound = errors.New("parent category not found")
+	ErrCategoryNotFound       = errors.New("category not found")
+)
+
+type Category struct {
+	ID        entity.ID  `json:"id"`
+	Name      string     `json:"name"`
+	ParentID  *entity.ID `json:"parent_id"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// CategoryNode is a category together with its subcategories.
+type CategoryNode struct {
+	Category
+	Children []*CategoryNode `json:"children"`
+}
+
+func (c *Category) Validate() error {
+	if c.ID.String() == "" {
+		return ErrIDIsRequired
+	}
+
+	if _, err := entity.ParseID(c.ID.String()); err != nil {
+		return ErrInvalidId
+	}
+	if c.Name == "" {
+		return ErrNameIsRequired
+	}
+	if c.ParentID != nil && *c.ParentID == c.ID {
+		return ErrCategoryCycle
+	}
+	return nil
+}
+
+func NewCategory(name string, parentID *entity.ID) (*Category, error) {
+	category := &Category{
+		ID:        entity.NewID(),
+		Name:      name,
+		ParentID:  parentID,
+		CreatedAt: time.Now(),
+	}
+
+	err := category.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return category, nil
+}
+
+// AuditFields are the fields of the category its audit entries track.
+func (c *Category) AuditFields() map[string]interface{} {
+	return map[string]interface{}{
+		"name":      c.Name,
+		"parent_id": c.ParentID,
+	}
+}
+
+// BuildCategoryTree arranges a flat list of categories into trees, returning
+// the roots. Categories whose parent is not in the list are treated as roots.
+func BuildCategoryTree(categories []Category) []*CategoryNode {
+	nodes := make(map[entity.ID]*CategoryNode, len(categories))
+	for _, c := range categories {
+		nodes[c.ID] = &CategoryNode{Category: c, Children: []*CategoryNode{}}
+	}
+
+	roots := []*CategoryNode{}
+	for _, c := range categories {
+		node := nodes[c.ID]
+		if c.ParentID != nil {
+			if parent, ok := nodes[*c.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+
+	return roots
+}
@@ -0,0 +1,51 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCategory(t *testing.T) {
+	category, err := NewCategory("Electronics", nil)
+	assert.Nil(t, err)
+	assert.NotNil(t, category)
+	assert.Equal(t, "Electronics", category.Name)
+	assert.Nil(t, category.ParentID)
+	assert.NotEmpty(t, category.ID)
+	assert.NotEmpty(t, category.CreatedAt)
+
+	child, err := NewCategory("Keyboards", &category.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, category.ID, *child.ParentID)
+}
+
+func TestCategoryValidations_Name(t *testing.T) {
+	category, err := NewCategory("", nil)
+	assert.Nil(t, category)
+	assert.Equal(t, ErrNameIsRequired, err)
+}
+
+func TestCategoryValidations_OwnParent(t *testing.T) {
+	category, err := NewCategory("Electronics", nil)
+	assert.Nil(t, err)
+
+	category.ParentID = &category.ID
+	assert.Equal(t, ErrCategoryCycle, category.Validate())
+}
+
+func TestBuildCategoryTree(t *testing.T) {
+	electronics, _ := NewCategory("Electronics", nil)
+	keyboards, _ := NewCategory("Keyboards", &electronics.ID)
+	mechanical, _ := NewCategory("Mechanical", &keyboards.ID)
+	books, _ := NewCategory("Books", nil)
+
+	roots := BuildCategoryTree([]Category{*mechanical, *electronics, *books, *keyboards})
+	assert.Len(t, roots, 2)
+	assert.Equal(t, "Electronics", roots[0].Name)
+	assert.Equal(t, "Books", roots[1].Name)
+	assert.Len(t, roots[0].Children, 1)
+	assert.Equal(t, "Keyboards", roots[0].Children[0].Name)
+	assert.Equal(t, "Mechanical", roots[0].Children[0].Children[0].Name)
+	assert.Empty(t, roots[1].Children)
+}
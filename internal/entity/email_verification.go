@@ -0,0 +1,45 @@
+package entity
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidVerificationToken = errors.New("invalid verification token")
+	ErrVerificationTokenExpired = errors.New("verification token expired")
+)
+
+// emailVerificationPurpose tells verification tokens apart from the other
+// payloads signed with the same key, such as cursors.
+const emailVerificationPurpose = "verify_email"
+
+// EmailVerification is the payload of the signed token in the link emailed on
+// signup. It is bound to the email it was sent to, so that a link to an
+// address the user no longer has cannot verify a new one.
+type EmailVerification struct {
+	Purpose   string `json:"purpose"`
+	UserID    string `json:"sub"`
+	Email     string `json:"email"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+func NewEmailVerification(user *User, expiresIn time.Duration) EmailVerification {
+	return EmailVerification{
+		Purpose:   emailVerificationPurpose,
+		UserID:    user.ID.String(),
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(expiresIn).Unix(),
+	}
+}
+
+// Validate checks whether the payload verifies the email of user.
+func (v EmailVerification) Validate(user *User, now time.Time) error {
+	if v.Purpose != emailVerificationPurpose || v.UserID != user.ID.String() || v.Email != user.Email {
+		return ErrInvalidVerificationToken
+	}
+	if now.Unix() >= v.ExpiresAt {
+		return ErrVerificationTokenExpired
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmailVerification_Validate(t *testing.T) {
+	user, err := NewUser("John Doe", "john@gmail.com", "123456")
+	assert.Nil(t, err)
+	assert.False(t, user.Verified)
+
+	verification := NewEmailVerification(user, time.Hour)
+	assert.Nil(t, verification.Validate(user, time.Now()))
+	assert.Equal(t, ErrVerificationTokenExpired, verification.Validate(user, time.Now().Add(2*time.Hour)))
+
+	other, _ := NewUser("Jane Doe", "jane@gmail.com", "123456")
+	assert.Equal(t, ErrInvalidVerificationToken, verification.Validate(other, time.Now()))
+
+	user.Email = "john@example.com"
+	assert.Equal(t, ErrInvalidVerificationToken, verification.Validate(user, time.Now()))
+
+	// other signed payloads are not verification tokens
+	user.Email = "john@gmail.com"
+	assert.Equal(t, ErrInvalidVerificationToken, EmailVerification{UserID: user.ID.String(), Email: user.Email, ExpiresAt: verification.ExpiresAt}.Validate(user, time.Now()))
+}
@@ -0,0 +1,90 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sallescosta/user-and-products-manager/pkg/entity"
+)
+
+var (
+	ErrImageIsRequired      = errors.New("image is required")
+	ErrUnsupportedImageType = errors.New("images must be JPEG, PNG, GIF or WebP")
+	ErrImageTooLarge        = errors.New("image is too large")
+	ErrInvalidImage         = errors.New("image cannot be decoded")
+	ErrInvalidImageOrder    = errors.New("the order must list every image of the product once")
+)
+
+// ImageExtensions are the content types images can be uploaded in, with the
+// extension their files are stored under.
+var ImageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// ThumbnailType is the content type of the thumbnails of images of
+// contentType: JPEG photos stay JPEG, the rest become PNG to keep transparency.
+func ThumbnailType(contentType string) string {
+	if contentType == "image/jpeg" {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
+
+// ProductImage is an image of a product, kept in storage under Key with its
+// thumbnail under ThumbnailKey. Images are shown in the order of Position,
+// starting at 0. URL and ThumbnailURL are filled in from the storage when the
+// image is returned.
+type ProductImage struct {
+	ID           entity.ID `json:"id"`
+	ProductID    entity.ID `json:"product_id" gorm:"index"`
+	Key          string    `json:"-" gorm:"size:255"`
+	ThumbnailKey string    `json:"-" gorm:"size:255"`
+	ContentType  string    `json:"content_type" gorm:"size:32"`
+	Size         int64     `json:"size"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	Position     int       `json:"position" gorm:"not null;default:0"`
+	CreatedAt    time.Time `json:"created_at"`
+	URL          string    `json:"url" gorm:"-"`
+	ThumbnailURL string    `json:"thumbnail_url" gorm:"-"`
+}
+
+// NewProductImage describes an image of the product of the given type, size in
+// bytes and dimensions, with the keys its files are to be stored under. Its
+// position is set when it is saved.
+func NewProductImage(productID entity.ID, contentType string, size int64, width, height int) (*ProductImage, error) {
+	ext, ok := ImageExtensions[contentType]
+	if !ok {
+		return nil, ErrUnsupportedImageType
+	}
+	if size <= 0 || width <= 0 || height <= 0 {
+		return nil, ErrInvalidImage
+	}
+
+	id := entity.NewID()
+	prefix := fmt.Sprintf("products/%s/%s", productID, id)
+	return &ProductImage{
+		ID:           id,
+		ProductID:    productID,
+		Key:          prefix + ext,
+		ThumbnailKey: prefix + "_thumb" + ImageExtensions[ThumbnailType(contentType)],
+		ContentType:  contentType,
+		Size:         size,
+		Width:        width,
+		Height:       height,
+		CreatedAt:    time.Now(),
+	}, nil
+}
+
+// AuditFields are the fields of the image its audit entries track.
+func (i *ProductImage) AuditFields() map[string]interface{} {
+	return map[string]interface{}{
+		"content_type": i.ContentType,
+		"size":         i.Size,
+		"position":     i.Position,
+	}
+}
@@ -0,0 +1,42 @@
+package entity
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sallescosta/user-and-products-manager/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewProductImage(t *testing.T) {
+	productID := entity.NewID()
+
+	image, err := NewProductImage(productID, "image/png", 2048, 640, 480)
+	assert.Nil(t, err)
+	assert.Equal(t, productID, image.ProductID)
+	assert.Equal(t, "products/"+productID.String()+"/"+image.ID.String()+".png", image.Key)
+	assert.Equal(t, "products/"+productID.String()+"/"+image.ID.String()+"_thumb.png", image.ThumbnailKey)
+
+	photo, err := NewProductImage(productID, "image/jpeg", 2048, 640, 480)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasSuffix(photo.ThumbnailKey, "_thumb.jpg"))
+
+	// transparent formats get PNG thumbnails
+	animation, err := NewProductImage(productID, "image/webp", 2048, 640, 480)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasSuffix(animation.Key, ".webp"))
+	assert.True(t, strings.HasSuffix(animation.ThumbnailKey, "_thumb.png"))
+}
+
+func TestProductImageValidations(t *testing.T) {
+	productID := entity.NewID()
+
+	_, err := NewProductImage(productID, "image/svg+xml", 2048, 640, 480)
+	assert.Equal(t, ErrUnsupportedImageType, err)
+
+	_, err = NewProductImage(productID, "image/png", 0, 640, 480)
+	assert.Equal(t, ErrInvalidImage, err)
+
+	_, err = NewProductImage(productID, "image/png", 2048, 0, 480)
+	assert.Equal(t, ErrInvalidImage, err)
+}
@@ -0,0 +1,65 @@
+package entity
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrTooManyLoginAttempts = errors.New("too many failed login attempts")
+
+// LoginLimit is how failed logins slow down the next ones, for a given kind
+// of key such as an account or an IP address.
+type LoginLimit struct {
+	// MaxFailures is how many failures in a row lock the key out.
+	MaxFailures int
+	// Backoff is the wait after the first failure, doubled after each of
+	// the following ones.
+	Backoff time.Duration
+	// Lockout is how long the key is locked out for after MaxFailures, and
+	// how long failures are remembered for.
+	Lockout time.Duration
+}
+
+// LoginAttempt counts the failed logins in a row of a key, such as
+// "account:john@example.com" or "ip:192.0.2.1".
+type LoginAttempt struct {
+	Key          string    `json:"key" gorm:"column:login_key;primaryKey;size:320"`
+	Failures     int       `json:"failures"`
+	BlockedUntil time.Time `json:"blocked_until"`
+	// ExpiresAt is when the failures are forgotten.
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+}
+
+// Fail counts a failed login at now, blocking the key for the backoff of its
+// number of failures, or locking it out once there are too many.
+func (a *LoginAttempt) Fail(now time.Time, limit LoginLimit) {
+	if !now.Before(a.ExpiresAt) {
+		a.Failures = 0
+	}
+	a.Failures++
+	a.Block(now, limit)
+}
+
+// Block sets how long the key is blocked for after its last failure, at now,
+// given its number of failures.
+func (a *LoginAttempt) Block(now time.Time, limit LoginLimit) {
+	wait := limit.Lockout
+	if a.Failures < limit.MaxFailures {
+		wait = limit.Backoff
+		for i := 1; i < a.Failures && wait < limit.Lockout; i++ {
+			wait *= 2
+		}
+		wait = min(wait, limit.Lockout)
+	}
+	a.BlockedUntil = now.Add(wait)
+	a.ExpiresAt = now.Add(limit.Lockout)
+}
+
+// RetryAfter is how long the key must wait before its next login, zero when
+// it may log in right away.
+func (a LoginAttempt) RetryAfter(now time.Time) time.Duration {
+	if now.Before(a.BlockedUntil) {
+		return a.BlockedUntil.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,35 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginAttempt_Fail(t *testing.T) {
+	limit := LoginLimit{MaxFailures: 4, Backoff: time.Second, Lockout: time.Minute}
+	now := time.Now()
+	attempt := LoginAttempt{Key: "account:john@gmail.com"}
+	assert.Equal(t, time.Duration(0), attempt.RetryAfter(now))
+
+	// the wait doubles after each failure, up to the lockout
+	for i, wait := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, time.Minute, time.Minute} {
+		attempt.Fail(now, limit)
+		assert.Equal(t, i+1, attempt.Failures)
+		assert.Equal(t, wait, attempt.RetryAfter(now))
+	}
+	assert.Equal(t, time.Duration(0), attempt.RetryAfter(now.Add(time.Minute)))
+
+	// failures are forgotten after the lockout
+	attempt.Fail(now.Add(time.Minute), limit)
+	assert.Equal(t, 1, attempt.Failures)
+	assert.Equal(t, time.Second, attempt.RetryAfter(now.Add(time.Minute)))
+
+	// the backoff never exceeds the lockout
+	attempt = LoginAttempt{}
+	for i := 0; i < 100; i++ {
+		attempt.Fail(now, LoginLimit{MaxFailures: 1000, Backoff: time.Second, Lockout: time.Hour})
+	}
+	assert.Equal(t, time.Hour, attempt.RetryAfter(now))
+}
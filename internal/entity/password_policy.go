@@ -0,0 +1,52 @@
+package entity
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrPasswordTooShort = errors.New("password is too short")
+	ErrPasswordBreached = errors.New("password is known from a data breach")
+)
+
+// PasswordPolicy is what the passwords users choose must satisfy, on top of
+// what SetPassword requires. A nil policy accepts every password.
+type PasswordPolicy struct {
+	// MinLength is the least number of characters of a password.
+	MinLength int
+	breached  map[string]struct{}
+}
+
+func NewPasswordPolicy(minLength int) *PasswordPolicy {
+	return &PasswordPolicy{MinLength: minLength, breached: map[string]struct{}{}}
+}
+
+// LoadBreached adds the passwords of r, one per line, to the ones refused
+// for having leaked, such as the lists published after data breaches.
+func (p *PasswordPolicy) LoadBreached(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if password := strings.TrimRight(scanner.Text(), "\r"); password != "" {
+			p.breached[password] = struct{}{}
+		}
+	}
+	return scanner.Err()
+}
+
+func (p *PasswordPolicy) Check(password string) error {
+	if p == nil {
+		return nil
+	}
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return fmt.Errorf("%w: it needs at least %d characters", ErrPasswordTooShort, p.MinLength)
+	}
+	if _, ok := p.breached[password]; ok {
+		return ErrPasswordBreached
+	}
+	return nil
+}
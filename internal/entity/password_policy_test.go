@@ -0,0 +1,23 @@
+package entity
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordPolicy_Check(t *testing.T) {
+	policy := NewPasswordPolicy(8)
+	assert.Nil(t, policy.LoadBreached(strings.NewReader("password\r\n12345678\n\nqwertyuiop\n")))
+
+	assert.Nil(t, policy.Check("correct horse"))
+	assert.Nil(t, policy.Check("ççççççç0"))
+	assert.ErrorIs(t, policy.Check("1234567"), ErrPasswordTooShort)
+	assert.ErrorIs(t, policy.Check("12345678"), ErrPasswordBreached)
+	assert.ErrorIs(t, policy.Check("qwertyuiop"), ErrPasswordBreached)
+	assert.Nil(t, policy.Check("Qwertyuiop"))
+
+	var none *PasswordPolicy
+	assert.Nil(t, none.Check("1"))
+}
@@ -0,0 +1,55 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/sallescosta/user-and-products-manager/pkg/entity"
+)
+
+var (
+	ErrInvalidResetToken = errors.New("invalid password reset token")
+	ErrResetTokenExpired = errors.New("password reset token expired")
+	ErrResetTokenUsed    = errors.New("password reset token already used")
+)
+
+// PasswordResetToken lets a user who forgot their password choose a new one,
+// once. Like refresh tokens, only the hash of the token is stored: the plain
+// value is only ever in the email sent to the user.
+type PasswordResetToken struct {
+	ID        entity.ID  `json:"id"`
+	UserID    entity.ID  `json:"user_id" gorm:"index"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// NewPasswordResetToken generates a random reset token for the user and
+// returns the record to persist together with the plain token to email.
+func NewPasswordResetToken(userID entity.ID, expiresIn time.Duration) (*PasswordResetToken, string, error) {
+	token, err := newOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	return &PasswordResetToken{
+		ID:        entity.NewID(),
+		UserID:    userID,
+		TokenHash: HashToken(token),
+		ExpiresAt: now.Add(expiresIn),
+		CreatedAt: now,
+	}, token, nil
+}
+
+// Validate checks whether the token can still be used to reset the password.
+func (t *PasswordResetToken) Validate(now time.Time) error {
+	if t.UsedAt != nil {
+		return ErrResetTokenUsed
+	}
+	if !now.Before(t.ExpiresAt) {
+		return ErrResetTokenExpired
+	}
+	return nil
+}
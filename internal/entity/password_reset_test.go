@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sallescosta/user-and-products-manager/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPasswordResetToken(t *testing.T) {
+	userID := entity.NewID()
+	token, plain, err := NewPasswordResetToken(userID, time.Hour)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, plain)
+	assert.Equal(t, userID, token.UserID)
+	assert.Equal(t, HashToken(plain), token.TokenHash)
+	assert.Nil(t, token.Validate(time.Now()))
+}
+
+func TestPasswordResetToken_Validate(t *testing.T) {
+	token, _, err := NewPasswordResetToken(entity.NewID(), time.Hour)
+	assert.Nil(t, err)
+
+	assert.Equal(t, ErrResetTokenExpired, token.Validate(time.Now().Add(2*time.Hour)))
+
+	now := time.Now()
+	token.UsedAt = &now
+	assert.Equal(t, ErrResetTokenUsed, token.Validate(now))
+}
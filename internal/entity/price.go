@@ -0,0 +1,95 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/sallescosta/user-and-products-manager/pkg/entity"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrEffectiveFromInPast   = errors.New("effective_from must be in the future")
+	ErrPriceAlreadyScheduled = errors.New("a price is already scheduled at that time")
+	ErrPriceNotScheduled     = errors.New("only prices that are still scheduled can be cancelled")
+)
+
+type PriceStatus string
+
+const (
+	// PricePast was the price of the product until EffectiveTo.
+	PricePast PriceStatus = "past"
+	// PriceCurrent is the price of the product now.
+	PriceCurrent PriceStatus = "current"
+	// PriceScheduled becomes the price of the product at EffectiveFrom.
+	PriceScheduled PriceStatus = "scheduled"
+)
+
+// ProductPrice is a period of the price timeline of a product. Every price a
+// product had is kept, from the moment it took effect until the next one did.
+// Scheduled prices are not applied yet: AppliedAt is nil until the scheduler
+// makes them current.
+type ProductPrice struct {
+	ID            entity.ID    `json:"id"`
+	ProductID     entity.ID    `json:"product_id" gorm:"index"`
+	Price         entity.Money `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	EffectiveFrom time.Time    `json:"effective_from" gorm:"index"`
+	EffectiveTo   *time.Time   `json:"effective_to"`
+	AppliedAt     *time.Time   `json:"applied_at"`
+	UserID        string       `json:"user_id,omitempty" gorm:"size:36"`
+	CreatedAt     time.Time    `json:"created_at"`
+	Status        PriceStatus  `json:"status" gorm:"-"`
+}
+
+// NewProductPrice records price as the price of the product from now on.
+func NewProductPrice(productID entity.ID, price entity.Money) *ProductPrice {
+	now := time.Now()
+	return &ProductPrice{
+		ID:            entity.NewID(),
+		ProductID:     productID,
+		Price:         price,
+		EffectiveFrom: now,
+		AppliedAt:     &now,
+		CreatedAt:     now,
+		Status:        PriceCurrent,
+	}
+}
+
+// NewScheduledPrice schedules price to become the price of the product at
+// effectiveFrom, which must be in the future.
+func NewScheduledPrice(productID, userID entity.ID, price entity.Money, effectiveFrom time.Time) (*ProductPrice, error) {
+	if err := price.Validate(); err != nil {
+		return nil, err
+	}
+	if price.IsZero() {
+		return nil, ErrPriceIsRequired
+	}
+	if price.IsNegative() {
+		return nil, ErrInvalidPrice
+	}
+	if !effectiveFrom.After(time.Now()) {
+		return nil, ErrEffectiveFromInPast
+	}
+
+	return &ProductPrice{
+		ID:            entity.NewID(),
+		ProductID:     productID,
+		Price:         price,
+		EffectiveFrom: effectiveFrom,
+		UserID:        userID.String(),
+		CreatedAt:     time.Now(),
+		Status:        PriceScheduled,
+	}, nil
+}
+
+func (p *ProductPrice) AfterFind(tx *gorm.DB) error {
+	switch {
+	case p.AppliedAt == nil:
+		p.Status = PriceScheduled
+	case p.EffectiveTo != nil:
+		p.Status = PricePast
+	default:
+		p.Status = PriceCurrent
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sallescosta/user-and-products-manager/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewScheduledPrice(t *testing.T) {
+	productID, userID := entity.NewID(), entity.NewID()
+	from := time.Now().Add(time.Hour)
+
+	price, err := NewScheduledPrice(productID, userID, usd(1299), from)
+	assert.Nil(t, err)
+	assert.Equal(t, productID, price.ProductID)
+	assert.Equal(t, userID.String(), price.UserID)
+	assert.Equal(t, from, price.EffectiveFrom)
+	assert.Nil(t, price.AppliedAt)
+	assert.Equal(t, PriceScheduled, price.Status)
+}
+
+func TestScheduledPriceValidations(t *testing.T) {
+	productID, userID := entity.NewID(), entity.NewID()
+	later := time.Now().Add(time.Hour)
+
+	_, err := NewScheduledPrice(productID, userID, usd(1299), time.Now().Add(-time.Minute))
+	assert.Equal(t, ErrEffectiveFromInPast, err)
+
+	_, err = NewScheduledPrice(productID, userID, usd(0), later)
+	assert.Equal(t, ErrPriceIsRequired, err)
+
+	_, err = NewScheduledPrice(productID, userID, usd(-1), later)
+	assert.Equal(t, ErrInvalidPrice, err)
+
+	_, err = NewScheduledPrice(productID, userID, entity.Money{Amount: 1, Currency: "XYZ"}, later)
+	assert.ErrorIs(t, err, entity.ErrInvalidCurrency)
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/sallescosta/user-and-products-manager/pkg/entity"
+	"gorm.io/gorm"
 )
 
 var (
@@ -13,13 +14,20 @@ var (
 	ErrPriceIsRequired = errors.New("price is required")
 	ErrInvalidPrice    = errors.New("invalid price")
 	ErrInvalidId       = errors.New("invalid id")
+	ErrVersionMismatch = errors.New("the product was changed by someone else")
 )
 
 type Product struct {
-	ID        entity.ID `json:"id"`
-	Name      string    `json:"name"`
-	Price     float64   `json:"price"`
-	CreatedAt time.Time `json:"created_at"`
+	ID         entity.ID      `json:"id"`
+	Name       string         `json:"name"`
+	Price      entity.Money   `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	Quantity   int            `json:"quantity" gorm:"not null;default:0"`
+	Version    int            `json:"version" gorm:"not null;default:1"`
+	CreatedAt  time.Time      `json:"created_at"`
+	DeletedAt  gorm.DeletedAt `json:"deleted_at" gorm:"index" swaggertype:"string" format:"date-time"`
+	Categories []Category     `json:"categories,omitempty" gorm:"many2many:product_categories"`
+	Variants   []Variant      `json:"variants,omitempty"`
+	Images     []ProductImage `json:"images,omitempty"`
 }
 
 func (p *Product) Validate() error {
@@ -33,21 +41,25 @@ func (p *Product) Validate() error {
 	if p.Name == "" {
 		return ErrNameIsRequired
 	}
-	if p.Price == 0 {
+	if err := p.Price.Validate(); err != nil {
+		return err
+	}
+	if p.Price.IsZero() {
 		return ErrPriceIsRequired
 	}
-	if p.Price < 0 {
+	if p.Price.IsNegative() {
 		return ErrInvalidPrice
 	}
 	return nil
 }
 
-func NewProduct(name string, price float64) (*Product, error) {
+func NewProduct(name string, price entity.Money) (*Product, error) {
 
 	product := &Product{
 		ID:        entity.NewID(),
 		Name:      name,
 		Price:     price,
+		Version:   1,
 		CreatedAt: time.Now(),
 	}
 
@@ -3,34 +3,49 @@ package entity
 import (
 	"testing"
 
+	"github.com/sallescosta/user-and-products-manager/pkg/entity"
 	"github.com/stretchr/testify/assert"
 )
 
+func usd(amount int64) entity.Money {
+	return entity.Money{Amount: amount, Currency: "USD"}
+}
+
 func TestNewProduct(t *testing.T) {
-	product, err := NewProduct("book", 15)
+	product, err := NewProduct("book", usd(1500))
 	assert.Nil(t, err)
 	assert.NotNil(t, product)
 	assert.Equal(t, "book", product.Name)
-	assert.Equal(t, 15, product.Price)
+	assert.Equal(t, usd(1500), product.Price)
 	assert.NotEmpty(t, product.ID)
 	assert.NotEmpty(t, product.CreatedAt)
 	assert.Nil(t, product.Validate())
 }
 
 func TestProductValidations_Name(t *testing.T) {
-	product, err := NewProduct("", 15)
+	product, err := NewProduct("", usd(1500))
 	assert.Nil(t, product)
 	assert.Equal(t, err, ErrNameIsRequired)
 }
 
 func TestProductValidations_No_Price(t *testing.T) {
-	product, err := NewProduct("book", 0)
+	product, err := NewProduct("book", usd(0))
 	assert.Nil(t, product)
 	assert.Equal(t, err, ErrPriceIsRequired)
 }
 
 func TestProductValidations_Invalid_Price(t *testing.T) {
-	product, err := NewProduct("book", -15)
+	product, err := NewProduct("book", usd(-1500))
 	assert.Nil(t, product)
 	assert.Equal(t, err, ErrInvalidPrice)
 }
+
+func TestProductValidations_Invalid_Currency(t *testing.T) {
+	product, err := NewProduct("book", entity.Money{Amount: 1500, Currency: "XYZ"})
+	assert.Nil(t, product)
+	assert.Equal(t, err, entity.ErrInvalidCurrency)
+
+	product, err = NewProduct("book", entity.Money{Amount: 1500})
+	assert.Nil(t, product)
+	assert.Equal(t, err, entity.ErrInvalidCurrency)
+}
@@ -0,0 +1,81 @@
+package entity
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/sallescosta/user-and-products-manager/pkg/entity"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenExpired = errors.New("refresh token expired")
+	ErrRefreshTokenRevoked = errors.New("refresh token revoked")
+)
+
+// RefreshToken is the server side record of a refresh token. Only the hash of
+// the token is stored, the plain value is handed to the client once.
+type RefreshToken struct {
+	ID        entity.ID  `json:"id"`
+	UserID    entity.ID  `json:"user_id" gorm:"index"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// NewRefreshToken generates a random refresh token for the user and returns the
+// record to persist together with the plain token to send to the client.
+func NewRefreshToken(userID entity.ID, expiresIn time.Duration) (*RefreshToken, string, error) {
+	token, err := newOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	return &RefreshToken{
+		ID:        entity.NewID(),
+		UserID:    userID,
+		TokenHash: HashToken(token),
+		ExpiresAt: now.Add(expiresIn),
+		CreatedAt: now,
+	}, token, nil
+}
+
+// newOpaqueToken returns 32 random bytes, URL safe encoded.
+func newOpaqueToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashToken returns the hex encoded SHA-256 of a plain token, which is the
+// value stored and looked up in the database.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Validate checks whether the refresh token can still be exchanged.
+func (t *RefreshToken) Validate(now time.Time) error {
+	if t.RevokedAt != nil {
+		return ErrRefreshTokenRevoked
+	}
+	if !now.Before(t.ExpiresAt) {
+		return ErrRefreshTokenExpired
+	}
+	return nil
+}
+
+// RevokedToken is an access token (identified by its "jti" claim) that must be
+// rejected until it expires on its own.
+type RevokedToken struct {
+	JTI       string    `json:"jti" gorm:"primaryKey"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+}
@@ -0,0 +1,31 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sallescosta/user-and-products-manager/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRefreshToken(t *testing.T) {
+	userID := entity.NewID()
+	token, plain, err := NewRefreshToken(userID, time.Hour)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, plain)
+	assert.Equal(t, userID, token.UserID)
+	assert.Equal(t, HashToken(plain), token.TokenHash)
+	assert.NotEqual(t, plain, token.TokenHash)
+	assert.Nil(t, token.Validate(time.Now()))
+}
+
+func TestRefreshToken_Validate(t *testing.T) {
+	token, _, err := NewRefreshToken(entity.NewID(), time.Hour)
+	assert.Nil(t, err)
+
+	assert.Equal(t, ErrRefreshTokenExpired, token.Validate(time.Now().Add(2*time.Hour)))
+
+	now := time.Now()
+	token.RevokedAt = &now
+	assert.Equal(t, ErrRefreshTokenRevoked, token.Validate(now))
+}
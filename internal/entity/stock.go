@@ -0,0 +1,89 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/sallescosta/user-and-products-manager/pkg/entity"
+)
+
+var (
+	ErrInvalidMovementType = errors.New("invalid stock movement type")
+	ErrInvalidQuantity     = errors.New("invalid quantity")
+	ErrReasonIsRequired    = errors.New("reason is required")
+	ErrInsufficientStock   = errors.New("insufficient stock")
+)
+
+type MovementType string
+
+const (
+	// MovementReceipt adds goods to the stock.
+	MovementReceipt MovementType = "receipt"
+	// MovementSale removes sold goods from the stock.
+	MovementSale MovementType = "sale"
+	// MovementAdjustment corrects the stock in either direction, e.g. after a
+	// count or for damaged goods, and always needs a reason.
+	MovementAdjustment MovementType = "adjustment"
+)
+
+// StockMovement is an entry of the append-only stock ledger of a product.
+// Quantity is signed: the sum of the movements of a product is its on-hand
+// quantity. Balance is the on-hand quantity right after the movement. The
+// movements of a variant have its VariantID, and count towards the quantity
+// of the variant instead.
+type StockMovement struct {
+	ID        entity.ID    `json:"id"`
+	ProductID entity.ID    `json:"product_id" gorm:"index"`
+	VariantID *entity.ID   `json:"variant_id,omitempty" gorm:"index"`
+	Type      MovementType `json:"type"`
+	Quantity  int          `json:"quantity"`
+	Balance   int          `json:"balance"`
+	Reason    string       `json:"reason"`
+	UserID    entity.ID    `json:"user_id"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// NewStockMovement builds a movement from the quantity as users enter it:
+// positive for receipts and sales, signed for adjustments.
+func NewStockMovement(productID, userID entity.ID, movementType MovementType, quantity int, reason string) (*StockMovement, error) {
+	switch movementType {
+	case MovementReceipt:
+		if quantity <= 0 {
+			return nil, ErrInvalidQuantity
+		}
+	case MovementSale:
+		if quantity <= 0 {
+			return nil, ErrInvalidQuantity
+		}
+		quantity = -quantity
+	case MovementAdjustment:
+		if quantity == 0 {
+			return nil, ErrInvalidQuantity
+		}
+		if reason == "" {
+			return nil, ErrReasonIsRequired
+		}
+	default:
+		return nil, ErrInvalidMovementType
+	}
+
+	return &StockMovement{
+		ID:        entity.NewID(),
+		ProductID: productID,
+		Type:      movementType,
+		Quantity:  quantity,
+		Reason:    reason,
+		UserID:    userID,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// AuditFields are the fields of the movement its audit entry tracks.
+func (m *StockMovement) AuditFields() map[string]interface{} {
+	return map[string]interface{}{
+		"type":     m.Type,
+		"quantity": m.Quantity,
+		"balance":  m.Balance,
+		"reason":   m.Reason,
+	}
+}
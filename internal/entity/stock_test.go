@@ -0,0 +1,43 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/sallescosta/user-and-products-manager/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewStockMovement(t *testing.T) {
+	productID, userID := entity.NewID(), entity.NewID()
+
+	receipt, err := NewStockMovement(productID, userID, MovementReceipt, 10, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 10, receipt.Quantity)
+	assert.Equal(t, productID, receipt.ProductID)
+	assert.Equal(t, userID, receipt.UserID)
+	assert.NotEmpty(t, receipt.ID)
+
+	sale, err := NewStockMovement(productID, userID, MovementSale, 3, "")
+	assert.Nil(t, err)
+	assert.Equal(t, -3, sale.Quantity)
+
+	adjustment, err := NewStockMovement(productID, userID, MovementAdjustment, -2, "damaged")
+	assert.Nil(t, err)
+	assert.Equal(t, -2, adjustment.Quantity)
+}
+
+func TestStockMovementValidations(t *testing.T) {
+	productID, userID := entity.NewID(), entity.NewID()
+
+	_, err := NewStockMovement(productID, userID, MovementReceipt, 0, "")
+	assert.Equal(t, ErrInvalidQuantity, err)
+
+	_, err = NewStockMovement(productID, userID, MovementSale, -1, "")
+	assert.Equal(t, ErrInvalidQuantity, err)
+
+	_, err = NewStockMovement(productID, userID, MovementAdjustment, 5, "")
+	assert.Equal(t, ErrReasonIsRequired, err)
+
+	_, err = NewStockMovement(productID, userID, "theft", 5, "")
+	assert.Equal(t, ErrInvalidMovementType, err)
+}
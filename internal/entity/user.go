@@ -1,36 +1,176 @@
 package entity
 
 import (
-	"log"
+	"errors"
+	netmail "net/mail"
+	"strings"
+	"sync"
 
 	"github.com/sallescosta/user-and-products-manager/pkg/entity"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	ErrInvalidRole        = errors.New("invalid role")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrPasswordIsRequired = errors.New("password is required")
+	ErrPasswordTooLong    = errors.New("password is longer than 72 bytes")
+	ErrEmailNotVerified   = errors.New("email not verified")
+	ErrEmailIsRequired    = errors.New("email is required")
+	ErrInvalidEmail       = errors.New("invalid email")
+	ErrEmailAlreadyExists = errors.New("email already exists")
+)
+
+// maxEmailLength is the longest address SMTP can deliver to.
+const maxEmailLength = 254
+
+// maxPasswordBytes is as much of a password as bcrypt takes into account.
+const maxPasswordBytes = 72
+
+type Role string
+
+const (
+	RoleAdmin  Role = "admin"
+	RoleEditor Role = "editor"
+	RoleViewer Role = "viewer"
+)
+
+// roleLevels orders roles so that a higher level includes every permission of
+// the lower ones: an admin can do everything an editor can, and so on.
+var roleLevels = map[Role]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if _, ok := roleLevels[role]; !ok {
+		return "", ErrInvalidRole
+	}
+	return role, nil
+}
+
+// Includes reports whether r grants at least the permissions of other.
+func (r Role) Includes(other Role) bool {
+	level, ok := roleLevels[r]
+	if !ok {
+		return false
+	}
+	return level >= roleLevels[other]
+}
+
 type User struct {
 	ID       entity.ID `json:"id"`
 	Name     string    `json:"name"`
-	Email    string    `json:"email"`
+	Email    string    `json:"email" gorm:"uniqueIndex"`
 	Password string    `json:"-"`
+	Role     Role      `json:"role" gorm:"not null;default:viewer"`
+	// Verified tells whether the user proved they own Email, through the
+	// link emailed on signup.
+	Verified bool `json:"verified" gorm:"not null;default:false"`
 }
 
 func NewUser(name, email, password string) (*User, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-
-	if err != nil {
-		log.Fatal(err)
+	user := &User{
+		ID:    entity.NewID(),
+		Name:  strings.TrimSpace(name),
+		Email: NormalizeEmail(email),
+		Role:  RoleViewer,
+	}
+	if err := user.SetPassword(password); err != nil {
+		return nil, err
+	}
+	if err := user.Validate(); err != nil {
 		return nil, err
 	}
+	return user, nil
+}
+
+// NormalizeEmail returns email the way it is stored: trimmed and in lower
+// case, so that an address is the same however it is typed.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// ValidateEmail checks that email is a bare address, "john@example.com",
+// without a display name or comments.
+func ValidateEmail(email string) error {
+	if email == "" {
+		return ErrEmailIsRequired
+	}
+	if len(email) > maxEmailLength {
+		return ErrInvalidEmail
+	}
+	address, err := netmail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return ErrInvalidEmail
+	}
+	_, domain, _ := strings.Cut(email, "@")
+	if domain == "" || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") || strings.Contains(domain, "..") {
+		return ErrInvalidEmail
+	}
+	return nil
+}
+
+func (u *User) Validate() error {
+	if u.ID.String() == "" {
+		return ErrIDIsRequired
+	}
+	if _, err := entity.ParseID(u.ID.String()); err != nil {
+		return ErrInvalidId
+	}
+	if u.Name == "" {
+		return ErrNameIsRequired
+	}
+	if u.Email != NormalizeEmail(u.Email) {
+		return ErrInvalidEmail
+	}
+	if err := ValidateEmail(u.Email); err != nil {
+		return err
+	}
+	if u.Password == "" {
+		return ErrPasswordIsRequired
+	}
+	if _, ok := roleLevels[u.Role]; !ok {
+		return ErrInvalidRole
+	}
+	return nil
+}
 
-	return &User{
-		ID:       entity.NewID(),
-		Name:     name,
-		Email:    email,
-		Password: string(hash),
-	}, nil
+// SetPassword replaces the password of the user, which is only stored
+// hashed.
+func (u *User) SetPassword(password string) error {
+	if password == "" {
+		return ErrPasswordIsRequired
+	}
+	if len(password) > maxPasswordBytes {
+		return ErrPasswordTooLong
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	u.Password = string(hash)
+	return nil
 }
 
 func (u *User) ValidatePassword(password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
 	return err == nil
 }
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// DummyPasswordCheck spends the time of ValidatePassword on a hash no password
+// matches, for logins with an unknown email to take as long as those with a
+// wrong password.
+func DummyPasswordCheck(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte(entity.NewID().String()), bcrypt.DefaultCost)
+	})
+	bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
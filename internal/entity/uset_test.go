@@ -1,6 +1,7 @@
 package entity
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -16,6 +17,34 @@ func TestNewUser(t *testing.T) {
 	assert.Equal(t, "john@gmail.com", user.Email)
 }
 
+func TestNewUser_Validation(t *testing.T) {
+	user, err := NewUser("  John Doe ", " John@Gmail.COM ", "123456")
+	assert.Nil(t, err)
+	assert.Equal(t, "John Doe", user.Name)
+	assert.Equal(t, "john@gmail.com", user.Email)
+	assert.Nil(t, user.Validate())
+
+	_, err = NewUser(" ", "john@gmail.com", "123456")
+	assert.Equal(t, ErrNameIsRequired, err)
+	_, err = NewUser("John Doe", "", "123456")
+	assert.Equal(t, ErrEmailIsRequired, err)
+	_, err = NewUser("John Doe", "john@gmail.com", "")
+	assert.Equal(t, ErrPasswordIsRequired, err)
+	_, err = NewUser("John Doe", "john@gmail.com", strings.Repeat("a", 73))
+	assert.Equal(t, ErrPasswordTooLong, err)
+
+	for _, email := range []string{"john", "john@", "@gmail.com", "John <john@gmail.com>", "john@gmail..com", "john@gmail.com.", "john doe@gmail.com", "john@gmail.com, jane@gmail.com"} {
+		_, err = NewUser("John Doe", email, "123456")
+		assert.Equal(t, ErrInvalidEmail, err, email)
+	}
+
+	user.Email = "John@gmail.com"
+	assert.Equal(t, ErrInvalidEmail, user.Validate())
+	user.Email = "john@gmail.com"
+	user.Role = "root"
+	assert.Equal(t, ErrInvalidRole, user.Validate())
+}
+
 func TestUser_ValidatePassword(t *testing.T) {
 	user, err := NewUser("John Doe", "john@gmail.com", "123456")
 	assert.Nil(t, err)
@@ -23,3 +52,39 @@ func TestUser_ValidatePassword(t *testing.T) {
 	assert.False(t, user.ValidatePassword("23456"))
 	assert.NotEqual(t, "123456", user.Password)
 }
+
+func TestNewUser_DefaultRole(t *testing.T) {
+	user, err := NewUser("John Doe", "john@gmail.com", "123456")
+	assert.Nil(t, err)
+	assert.Equal(t, RoleViewer, user.Role)
+}
+
+func TestParseRole(t *testing.T) {
+	role, err := ParseRole("editor")
+	assert.Nil(t, err)
+	assert.Equal(t, RoleEditor, role)
+
+	_, err = ParseRole("root")
+	assert.Equal(t, ErrInvalidRole, err)
+}
+
+func TestRole_Includes(t *testing.T) {
+	assert.True(t, RoleAdmin.Includes(RoleEditor))
+	assert.True(t, RoleAdmin.Includes(RoleViewer))
+	assert.True(t, RoleEditor.Includes(RoleEditor))
+	assert.False(t, RoleEditor.Includes(RoleAdmin))
+	assert.False(t, RoleViewer.Includes(RoleEditor))
+	assert.False(t, Role("").Includes(RoleViewer))
+}
+
+func TestUser_SetPassword(t *testing.T) {
+	user, err := NewUser("John Doe", "john@gmail.com", "123456")
+	assert.Nil(t, err)
+
+	assert.Nil(t, user.SetPassword("654321"))
+	assert.True(t, user.ValidatePassword("654321"))
+	assert.False(t, user.ValidatePassword("123456"))
+
+	assert.Equal(t, ErrPasswordIsRequired, user.SetPassword(""))
+	assert.True(t, user.ValidatePassword("654321"))
+}
@@ -0,0 +1,131 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sallescosta/user-and-products-manager/pkg/entity"
+)
+
+var (
+	ErrSKUIsRequired    = errors.New("sku is required")
+	ErrInvalidSKU       = errors.New("sku may only contain letters, digits, '-', '_' and '.', up to 64 characters")
+	ErrSKUAlreadyExists = errors.New("sku already exists")
+	ErrInvalidAttribute = errors.New("attribute names cannot be empty")
+)
+
+var skuPattern = regexp.MustCompile(`^[A-Z0-9._-]{1,64}$`)
+
+// VariantAttributes are the options that tell the variants of a product
+// apart, such as {"size": "M", "color": "red"}. They are stored as JSON.
+type VariantAttributes map[string]string
+
+func (a VariantAttributes) Value() (driver.Value, error) {
+	if a == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(a)
+	return string(b), err
+}
+
+func (a *VariantAttributes) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*a = VariantAttributes{}
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), a)
+	case []byte:
+		return json.Unmarshal(v, a)
+	}
+	return fmt.Errorf("cannot scan %T into VariantAttributes", value)
+}
+
+// Variant is a sellable version of a product, identified by its SKU. It has
+// its own stock, and its own price when Price is set; otherwise it is sold at
+// the price of the product.
+type Variant struct {
+	ID         entity.ID         `json:"id"`
+	ProductID  entity.ID         `json:"product_id" gorm:"index"`
+	SKU        string            `json:"sku" gorm:"size:64;uniqueIndex"`
+	Attributes VariantAttributes `json:"attributes" gorm:"type:text" swaggertype:"object,string"`
+	Price      *entity.Money     `json:"price,omitempty" gorm:"embedded;embeddedPrefix:price_"`
+	Quantity   int               `json:"quantity" gorm:"not null;default:0"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+// NormalizeSKU is the form SKUs are stored and compared in: trimmed and upper
+// case, so that "ab-1" and "AB-1" are the same SKU.
+func NormalizeSKU(sku string) string {
+	return strings.ToUpper(strings.TrimSpace(sku))
+}
+
+func (v *Variant) Validate() error {
+	if v.ID.String() == "" {
+		return ErrIDIsRequired
+	}
+	if _, err := entity.ParseID(v.ID.String()); err != nil {
+		return ErrInvalidId
+	}
+	if v.SKU == "" {
+		return ErrSKUIsRequired
+	}
+	if !skuPattern.MatchString(v.SKU) {
+		return ErrInvalidSKU
+	}
+	for name := range v.Attributes {
+		if strings.TrimSpace(name) == "" {
+			return ErrInvalidAttribute
+		}
+	}
+	if v.Price != nil {
+		if err := v.Price.Validate(); err != nil {
+			return err
+		}
+		if v.Price.IsZero() || v.Price.IsNegative() {
+			return ErrInvalidPrice
+		}
+	}
+	if v.Quantity < 0 {
+		return ErrInvalidQuantity
+	}
+	return nil
+}
+
+// NewVariant builds a variant of the product. A nil price sells it at the
+// price of the product.
+func NewVariant(productID entity.ID, sku string, attributes VariantAttributes, price *entity.Money, quantity int) (*Variant, error) {
+	if attributes == nil {
+		attributes = VariantAttributes{}
+	}
+
+	variant := &Variant{
+		ID:         entity.NewID(),
+		ProductID:  productID,
+		SKU:        NormalizeSKU(sku),
+		Attributes: attributes,
+		Price:      price,
+		Quantity:   quantity,
+		CreatedAt:  time.Now(),
+	}
+
+	if err := variant.Validate(); err != nil {
+		return nil, err
+	}
+	return variant, nil
+}
+
+// AuditFields are the fields of the variant its audit entries track.
+func (v *Variant) AuditFields() map[string]interface{} {
+	return map[string]interface{}{
+		"sku":        v.SKU,
+		"attributes": v.Attributes,
+		"price":      v.Price,
+		"quantity":   v.Quantity,
+	}
+}
@@ -0,0 +1,48 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/sallescosta/user-and-products-manager/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewVariant(t *testing.T) {
+	productID := entity.NewID()
+	price := usd(1599)
+
+	variant, err := NewVariant(productID, " tee-red-m ", VariantAttributes{"color": "red", "size": "M"}, &price, 5)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, variant.ID)
+	assert.Equal(t, productID, variant.ProductID)
+	assert.Equal(t, "TEE-RED-M", variant.SKU)
+	assert.Equal(t, "M", variant.Attributes["size"])
+	assert.Equal(t, &price, variant.Price)
+	assert.Equal(t, 5, variant.Quantity)
+
+	inherited, err := NewVariant(productID, "TEE-RED-L", nil, nil, 0)
+	assert.Nil(t, err)
+	assert.Nil(t, inherited.Price)
+	assert.NotNil(t, inherited.Attributes)
+}
+
+func TestVariantValidations(t *testing.T) {
+	productID := entity.NewID()
+	zero, negative := usd(0), usd(-1)
+
+	cases := map[error]func() (*Variant, error){
+		ErrSKUIsRequired:    func() (*Variant, error) { return NewVariant(productID, " ", nil, nil, 0) },
+		ErrInvalidSKU:       func() (*Variant, error) { return NewVariant(productID, "tee red", nil, nil, 0) },
+		ErrInvalidAttribute: func() (*Variant, error) { return NewVariant(productID, "TEE", VariantAttributes{"": "x"}, nil, 0) },
+		ErrInvalidPrice:     func() (*Variant, error) { return NewVariant(productID, "TEE", nil, &zero, 0) },
+		ErrInvalidQuantity:  func() (*Variant, error) { return NewVariant(productID, "TEE", nil, nil, -1) },
+	}
+	for want, build := range cases {
+		variant, err := build()
+		assert.Nil(t, variant)
+		assert.Equal(t, want, err)
+	}
+
+	_, err := NewVariant(productID, "TEE", nil, &negative, 0)
+	assert.Equal(t, ErrInvalidPrice, err)
+}
@@ -0,0 +1,181 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	entityPkg "github.com/sallescosta/user-and-products-manager/pkg/entity"
+	"gorm.io/gorm"
+)
+
+// AuditActor identifies who makes the changes of a request, for the audit
+// entries they leave.
+type AuditActor struct {
+	UserID    string
+	RequestID string
+}
+
+type auditActorKey struct{}
+
+// WithAuditActor returns a context whose writes are attributed to actor. The
+// audited repositories take it through their WithContext method, which binds
+// their queries to it, so that recordAudit finds the actor on the context of
+// the transaction.
+func WithAuditActor(ctx context.Context, actor AuditActor) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+// AuditActorFrom returns the actor of ctx, zero when there is none.
+func AuditActorFrom(ctx context.Context) AuditActor {
+	actor, _ := ctx.Value(auditActorKey{}).(AuditActor)
+	return actor
+}
+
+// recordAudit stores entry within tx, attributed to the actor of the context
+// of tx. Writes without an actor are the server's own.
+func recordAudit(tx *gorm.DB, entry *entity.AuditEntry) error {
+	if ctx := tx.Statement.Context; ctx != nil {
+		actor := AuditActorFrom(ctx)
+		entry.ActorID, entry.RequestID = actor.UserID, actor.RequestID
+	}
+	return tx.Create(entry).Error
+}
+
+type Audit struct {
+	DB *gorm.DB
+}
+
+func NewAudit(db *gorm.DB) *Audit {
+	return &Audit{DB: db}
+}
+
+// AuditQuery selects audit entries. Zero values mean no filter.
+type AuditQuery struct {
+	Page  int
+	Limit int
+
+	EntityType string
+	EntityID   string
+	ProductID  string
+	ActorID    string
+	Action     entity.AuditAction
+	RequestID  string
+	From       *time.Time
+	To         *time.Time
+}
+
+var auditQueryParameters = map[string]bool{
+	"page": true, "limit": true, "entity_type": true, "entity_id": true,
+	"product_id": true, "actor": true, "action": true, "request_id": true, "from": true, "to": true,
+}
+
+// ParseAuditQuery reads an AuditQuery from the query string of the audit log:
+//
+//	?entity_type=product&entity_id={id}&product_id={id}&actor={user id}&action=update
+//	&request_id={id}&from=2024-01-01&to=2024-01-31&page=1&limit=10
+func ParseAuditQuery(values url.Values) (AuditQuery, error) {
+	var q AuditQuery
+
+	for key := range values {
+		if !auditQueryParameters[key] {
+			return q, fmt.Errorf("%w: %s", ErrUnknownQueryParameter, key)
+		}
+	}
+
+	q.Page = nonNegative(values.Get("page"))
+	q.Limit = nonNegative(values.Get("limit"))
+	q.EntityType = values.Get("entity_type")
+	q.RequestID = values.Get("request_id")
+
+	for key, id := range map[string]*string{"entity_id": &q.EntityID, "product_id": &q.ProductID, "actor": &q.ActorID} {
+		if s := values.Get(key); s != "" {
+			if _, err := entityPkg.ParseID(s); err != nil {
+				return q, entity.ErrInvalidId
+			}
+			*id = s
+		}
+	}
+
+	if s := values.Get("action"); s != "" {
+		action, err := entity.ParseAuditAction(s)
+		if err != nil {
+			return q, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+		}
+		q.Action = action
+	}
+
+	var err error
+	if q.From, err = parseTimeFilter(values, "from", false); err != nil {
+		return q, err
+	}
+	if q.To, err = parseTimeFilter(values, "to", true); err != nil {
+		return q, err
+	}
+
+	return q, nil
+}
+
+// AuditResponse is a page of audit entries, with the links to the
+// neighbouring pages filled in by the web layer.
+type AuditResponse struct {
+	Total      int                 `json:"total"`
+	TotalPages int                 `json:"total_pages"`
+	Page       int                 `json:"page"`
+	Limit      int                 `json:"limit"`
+	Next       string              `json:"next,omitempty"`
+	Prev       string              `json:"prev,omitempty"`
+	Entries    []entity.AuditEntry `json:"entries"`
+}
+
+// FindByQuery lists the audit entries matching q, newest first.
+func (a *Audit) FindByQuery(q AuditQuery) (AuditResponse, error) {
+	response := AuditResponse{Limit: q.Limit, Entries: []entity.AuditEntry{}}
+
+	count := q.filter(a.DB.Model(&entity.AuditEntry{}))
+	find := q.filter(a.DB).Order("created_at desc").Order("id desc")
+	info, err := paginate(count, find, q.Page, q.Limit, &response.Entries)
+	response.Total, response.TotalPages, response.Page = info.Total, info.TotalPages, info.Page
+	return response, err
+}
+
+// filter adds the conditions of q to query.
+func (q AuditQuery) filter(query *gorm.DB) *gorm.DB {
+	filters := []struct {
+		condition string
+		value     string
+	}{
+		{"entity_type = ?", q.EntityType},
+		{"entity_id = ?", q.EntityID},
+		{"product_id = ?", q.ProductID},
+		{"actor_id = ?", q.ActorID},
+		{"action = ?", string(q.Action)},
+		{"request_id = ?", q.RequestID},
+	}
+	for _, filter := range filters {
+		if filter.value != "" {
+			query = query.Where(filter.condition, filter.value)
+		}
+	}
+	if q.From != nil {
+		query = query.Where("created_at >= ?", *q.From)
+	}
+	if q.To != nil {
+		query = query.Where("created_at <= ?", *q.To)
+	}
+	return query
+}
+
+// ProductHistory lists the entries of the product with id and of its variants,
+// images and stock movements, newest first. A product without any is only
+// found when it still exists, trashed or not, since purged ones keep the entry
+// of their purge.
+func (a *Audit) ProductHistory(id string, page, limit int) (AuditResponse, error) {
+	response, err := a.FindByQuery(AuditQuery{Page: page, Limit: limit, ProductID: id})
+	if err != nil || response.Total > 0 {
+		return response, err
+	}
+	return response, a.DB.Unscoped().Select("id").First(&entity.Product{}, "id = ?", id).Error
+}
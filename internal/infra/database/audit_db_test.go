@@ -0,0 +1,186 @@
+package database
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	pkgEntity "github.com/sallescosta/user-and-products-manager/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestProductAudit(t *testing.T) {
+	db := NewTestDB(t)
+	auditDB := NewAudit(db)
+
+	userID := pkgEntity.NewID().String()
+	ctx := WithAuditActor(context.Background(), AuditActor{UserID: userID, RequestID: "req-1"})
+	productDB := NewProduct(db).WithContext(ctx)
+
+	product, _ := entity.NewProduct(name, price)
+	assert.NoError(t, productDB.Create(product))
+
+	product.Price = pkgEntity.Money{Amount: 1299, Currency: "USD"}
+	assert.NoError(t, productDB.Update(product))
+
+	// a failed write leaves no entry
+	stale := *product
+	stale.Version = 1
+	assert.ErrorIs(t, productDB.Update(&stale), entity.ErrVersionMismatch)
+
+	category, _ := entity.NewCategory("Peripherals", nil)
+	assert.NoError(t, NewCategory(db).Create(category))
+	assert.NoError(t, productDB.SetCategories(product.ID.String(), []string{category.ID.String()}))
+	product.Version++
+
+	assert.NoError(t, productDB.Delete(product.ID.String(), product.Version))
+	assert.NoError(t, productDB.Restore(product.ID.String()))
+
+	// the purge job runs without an actor
+	assert.NoError(t, productDB.Delete(product.ID.String(), product.Version))
+	_, err := NewProduct(db).Purge(time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+
+	history, err := auditDB.FindByQuery(AuditQuery{EntityType: "product", EntityID: product.ID.String()})
+	assert.NoError(t, err)
+	assert.Equal(t, 7, history.Total)
+
+	var actions []entity.AuditAction
+	for _, entry := range history.Entries {
+		actions = append(actions, entry.Action)
+	}
+	assert.Equal(t, []entity.AuditAction{
+		entity.AuditPurge, entity.AuditDelete, entity.AuditRestore, entity.AuditDelete,
+		entity.AuditUpdate, entity.AuditUpdate, entity.AuditCreate,
+	}, actions)
+
+	purge, update, create := history.Entries[0], history.Entries[5], history.Entries[6]
+	assert.Empty(t, purge.ActorID)
+	assert.Equal(t, userID, update.ActorID)
+	assert.Equal(t, "req-1", update.RequestID)
+	assert.Equal(t, entity.AuditChanges{"price": {
+		From: map[string]interface{}{"amount": float64(1034), "currency": "USD"},
+		To:   map[string]interface{}{"amount": float64(1299), "currency": "USD"},
+	}}, update.Changes)
+	assert.Equal(t, entity.AuditChanges{"categories": {
+		From: []interface{}{},
+		To:   []interface{}{category.ID.String()},
+	}}, history.Entries[4].Changes)
+	assert.Equal(t, "Product 1", create.Changes["name"].To)
+
+	byActor, err := auditDB.FindByQuery(AuditQuery{ActorID: userID, Action: entity.AuditDelete})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, byActor.Total)
+
+	paged, err := auditDB.FindByQuery(AuditQuery{EntityID: product.ID.String(), Page: 2, Limit: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, paged.TotalPages)
+	assert.Len(t, paged.Entries, 3)
+	assert.Equal(t, history.Entries[3].ID, paged.Entries[0].ID)
+}
+
+func TestProductHistory(t *testing.T) {
+	db := NewTestDB(t)
+	auditDB := NewAudit(db)
+	productDB := NewProduct(db)
+
+	product, _ := entity.NewProduct(name, price)
+	assert.NoError(t, productDB.Create(product))
+	variant, _ := entity.NewVariant(product.ID, "SKU-1", nil, nil, 0)
+	assert.NoError(t, NewVariant(db).Create(variant))
+	image, _ := entity.NewProductImage(product.ID, "image/png", 1024, 800, 600)
+	assert.NoError(t, NewImage(db).Create(image))
+	other, _ := entity.NewProduct("Other", price)
+	assert.NoError(t, productDB.Create(other))
+
+	history, err := auditDB.ProductHistory(product.ID.String(), 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, history.Total)
+	assert.ElementsMatch(t, []string{"product", "variant", "image"},
+		[]string{history.Entries[0].EntityType, history.Entries[1].EntityType, history.Entries[2].EntityType})
+
+	// products older than the audit log have no entries
+	assert.NoError(t, db.Where("product_id = ?", other.ID).Delete(&entity.AuditEntry{}).Error)
+	history, err = auditDB.ProductHistory(other.ID.String(), 0, 0)
+	assert.NoError(t, err)
+	assert.Zero(t, history.Total)
+
+	_, err = auditDB.ProductHistory(pkgEntity.NewID().String(), 0, 0)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestCategoryAndStockAudit(t *testing.T) {
+	db := NewTestDB(t)
+	auditDB := NewAudit(db)
+
+	userID := pkgEntity.NewID()
+	ctx := WithAuditActor(context.Background(), AuditActor{UserID: userID.String(), RequestID: "req-1"})
+	categoryDB := NewCategory(db).WithContext(ctx)
+	productDB := NewProduct(db).WithContext(ctx)
+
+	category, _ := entity.NewCategory("Books", nil)
+	assert.NoError(t, categoryDB.Create(category))
+	category.Name = "Literature"
+	assert.NoError(t, categoryDB.Update(category))
+
+	product, _ := entity.NewProduct(name, price)
+	assert.NoError(t, productDB.Create(product))
+	assert.NoError(t, productDB.SetCategories(product.ID.String(), []string{category.ID.String()}))
+	assert.NoError(t, categoryDB.Delete(category.ID.String()))
+
+	entries, err := auditDB.FindByQuery(AuditQuery{EntityType: "category", EntityID: category.ID.String()})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, entries.Total)
+	assert.Equal(t, entity.AuditDelete, entries.Entries[0].Action)
+	assert.Equal(t, entity.AuditChanges{"name": {From: "Books", To: "Literature"}}, entries.Entries[1].Changes)
+	assert.Equal(t, userID.String(), entries.Entries[1].ActorID)
+	assert.Nil(t, entries.Entries[1].ProductID)
+
+	// the products that lose the category have it in their history
+	history, err := auditDB.ProductHistory(product.ID.String(), 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.AuditChanges{"categories": {
+		From: []interface{}{category.ID.String()},
+		To:   []interface{}{},
+	}}, history.Entries[0].Changes)
+
+	movement, _ := entity.NewStockMovement(product.ID, userID, entity.MovementReceipt, 10, "")
+	assert.NoError(t, NewStock(db).WithContext(ctx).Record(movement, false))
+
+	history, err = auditDB.ProductHistory(product.ID.String(), 0, 0)
+	assert.NoError(t, err)
+	stock := history.Entries[0]
+	assert.Equal(t, "stock", stock.EntityType)
+	assert.Equal(t, movement.ID, stock.EntityID)
+	assert.Equal(t, entity.AuditCreate, stock.Action)
+	assert.Equal(t, "req-1", stock.RequestID)
+	assert.Equal(t, float64(10), stock.Changes["balance"].To)
+}
+
+func TestParseAuditQuery(t *testing.T) {
+	id := pkgEntity.NewID().String()
+	values := url.Values{"entity_id": {id}, "product_id": {id}, "action": {"update"}, "from": {"2024-01-01"}, "page": {"2"}}
+
+	q, err := ParseAuditQuery(values)
+	assert.NoError(t, err)
+	assert.Equal(t, id, q.EntityID)
+	assert.Equal(t, id, q.ProductID)
+	assert.Equal(t, entity.AuditUpdate, q.Action)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), *q.From)
+	assert.Equal(t, 2, q.Page)
+
+	cases := map[string]error{
+		"color=red":       ErrUnknownQueryParameter,
+		"actor=someone":   entity.ErrInvalidId,
+		"action=rename":   ErrInvalidFilter,
+		"to=next-tuesday": ErrInvalidFilter,
+	}
+	for raw, want := range cases {
+		values, _ := url.ParseQuery(raw)
+		_, err := ParseAuditQuery(values)
+		assert.ErrorIs(t, err, want, raw)
+	}
+}
@@ -0,0 +1,206 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	"gorm.io/gorm"
+)
+
+type Category struct {
+	DB *gorm.DB
+}
+
+func NewCategory(db *gorm.DB) *Category {
+	return &Category{DB: db}
+}
+
+// WithContext returns the repository bound to ctx.
+func (c *Category) WithContext(ctx context.Context) CategoryInterface {
+	return &Category{DB: c.DB.WithContext(ctx)}
+}
+
+func categoryAudit(tx *gorm.DB, category *entity.Category, action entity.AuditAction, before, after map[string]interface{}) error {
+	return recordAudit(tx, entity.NewAuditEntry("category", category.ID, action, before, after))
+}
+
+func (c *Category) Create(category *entity.Category) error {
+	if err := c.checkParent(category); err != nil {
+		return err
+	}
+	return c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(category).Error; err != nil {
+			return err
+		}
+		return categoryAudit(tx, category, entity.AuditCreate, nil, category.AuditFields())
+	})
+}
+
+func (c *Category) FindById(id string) (*entity.Category, error) {
+	var category entity.Category
+	err := c.DB.First(&category, "id = ?", id).Error
+	return &category, err
+}
+
+func (c *Category) FindAll() ([]entity.Category, error) {
+	var categories []entity.Category
+	err := c.DB.Order("name").Find(&categories).Error
+	return categories, err
+}
+
+func (c *Category) Update(category *entity.Category) error {
+	old, err := c.FindById(category.ID.String())
+	if err != nil {
+		return err
+	}
+	if err = c.checkParent(category); err != nil {
+		return err
+	}
+	return c.DB.Transaction(func(tx *gorm.DB) error {
+		// the products carry the category in their body, so their ETags change
+		if err := touchCategoryProducts(tx, category.ID.String()); err != nil {
+			return err
+		}
+		if err := tx.Save(category).Error; err != nil {
+			return err
+		}
+		return categoryAudit(tx, category, entity.AuditUpdate, old.AuditFields(), category.AuditFields())
+	})
+}
+
+// Delete removes a category and its product assignments, which are audited as
+// changes of the categories of the products. Categories that still have
+// subcategories cannot be deleted.
+func (c *Category) Delete(id string) error {
+	category, err := c.FindById(id)
+	if err != nil {
+		return err
+	}
+
+	var children int64
+	if err = c.DB.Model(&entity.Category{}).Where("parent_id = ?", id).Count(&children).Error; err != nil {
+		return err
+	}
+	if children > 0 {
+		return entity.ErrCategoryHasChildren
+	}
+
+	return c.DB.Transaction(func(tx *gorm.DB) error {
+		// the products lose the category, so their ETags change
+		if err := touchCategoryProducts(tx, id); err != nil {
+			return err
+		}
+		if err := unassignCategory(tx, id); err != nil {
+			return err
+		}
+		if err := tx.Delete(category).Error; err != nil {
+			return err
+		}
+		return categoryAudit(tx, category, entity.AuditDelete, category.AuditFields(), nil)
+	})
+}
+
+// touchCategoryProducts bumps the version of every product assigned to the
+// category.
+func touchCategoryProducts(tx *gorm.DB, id string) error {
+	var productIDs []string
+	if err := tx.Table("product_categories").Where("category_id = ?", id).Pluck("product_id", &productIDs).Error; err != nil {
+		return err
+	}
+	for _, productID := range productIDs {
+		if err := touchProduct(tx, productID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unassignCategory removes the category with id from every product it is
+// assigned to, recording the change of their categories.
+func unassignCategory(tx *gorm.DB, id string) error {
+	var products []entity.Product
+	if err := tx.Unscoped().Preload("Categories").
+		Where("id IN (?)", tx.Table("product_categories").Select("product_id").Where("category_id = ?", id)).
+		Find(&products).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Exec("DELETE FROM product_categories WHERE category_id = ?", id).Error; err != nil {
+		return err
+	}
+
+	for _, product := range products {
+		before := categoryIDList(product.Categories)
+		after := make([]string, 0, len(before))
+		for _, categoryID := range before {
+			if categoryID != id {
+				after = append(after, categoryID)
+			}
+		}
+		err := productAudit(tx, product.ID, entity.AuditUpdate,
+			map[string]interface{}{"categories": before},
+			map[string]interface{}{"categories": after})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Descendants returns the IDs of the category and of every category below it.
+func (c *Category) Descendants(id string) ([]string, error) {
+	if _, err := c.FindById(id); err != nil {
+		return nil, err
+	}
+
+	ids := []string{id}
+	level := []string{id}
+	for len(level) > 0 {
+		var children []entity.Category
+		if err := c.DB.Select("id").Where("parent_id IN ?", level).Find(&children).Error; err != nil {
+			return nil, err
+		}
+
+		level = level[:0]
+		for _, child := range children {
+			ids = append(ids, child.ID.String())
+			level = append(level, child.ID.String())
+		}
+	}
+
+	return ids, nil
+}
+
+// checkParent makes sure the parent exists and is not the category itself or
+// one of its descendants, which would turn the tree into a cycle.
+func (c *Category) checkParent(category *entity.Category) error {
+	if category.ParentID == nil {
+		return nil
+	}
+
+	_, err := c.FindById(category.ParentID.String())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return entity.ErrParentCategoryNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	var count int64
+	if err = c.DB.Model(&entity.Category{}).Where("id = ?", category.ID).Count(&count).Error; err != nil || count == 0 {
+		return err
+	}
+
+	descendants, err := c.Descendants(category.ID.String())
+	if err != nil {
+		return err
+	}
+	for _, id := range descendants {
+		if id == category.ParentID.String() {
+			return entity.ErrCategoryCycle
+		}
+	}
+
+	return nil
+}
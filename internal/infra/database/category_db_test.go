@@ -0,0 +1,103 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	pkgEntity "github.com/sallescosta/user-and-products-manager/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateCategory(t *testing.T) {
+	db := NewTestDB(t)
+	categoryDB := NewCategory(db)
+
+	parent, _ := entity.NewCategory("Electronics", nil)
+	assert.NoError(t, categoryDB.Create(parent))
+
+	child, _ := entity.NewCategory("Keyboards", &parent.ID)
+	assert.NoError(t, categoryDB.Create(child))
+
+	found, err := categoryDB.FindById(child.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, parent.ID, *found.ParentID)
+
+	unknown := pkgEntity.NewID()
+	orphan, _ := entity.NewCategory("Orphan", &unknown)
+	assert.Equal(t, entity.ErrParentCategoryNotFound, categoryDB.Create(orphan))
+}
+
+func TestCategoryDescendantsAndCycles(t *testing.T) {
+	db := NewTestDB(t)
+	categoryDB := NewCategory(db)
+
+	electronics, _ := entity.NewCategory("Electronics", nil)
+	keyboards, _ := entity.NewCategory("Keyboards", &electronics.ID)
+	mechanical, _ := entity.NewCategory("Mechanical", &keyboards.ID)
+	books, _ := entity.NewCategory("Books", nil)
+	for _, c := range []*entity.Category{electronics, keyboards, mechanical, books} {
+		assert.NoError(t, categoryDB.Create(c))
+	}
+
+	ids, err := categoryDB.Descendants(electronics.ID.String())
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{electronics.ID.String(), keyboards.ID.String(), mechanical.ID.String()}, ids)
+
+	electronics.ParentID = &mechanical.ID
+	assert.Equal(t, entity.ErrCategoryCycle, categoryDB.Update(electronics))
+
+	electronics.ParentID = &books.ID
+	assert.NoError(t, categoryDB.Update(electronics))
+
+	assert.Equal(t, entity.ErrCategoryHasChildren, categoryDB.Delete(keyboards.ID.String()))
+	assert.NoError(t, categoryDB.Delete(mechanical.ID.String()))
+}
+
+func TestFindAllProductsByCategory(t *testing.T) {
+	db := NewTestDB(t)
+	categoryDB := NewCategory(db)
+	productDB := NewProduct(db)
+
+	electronics, _ := entity.NewCategory("Electronics", nil)
+	keyboards, _ := entity.NewCategory("Keyboards", &electronics.ID)
+	books, _ := entity.NewCategory("Books", nil)
+	for _, c := range []*entity.Category{electronics, keyboards, books} {
+		assert.NoError(t, categoryDB.Create(c))
+	}
+
+	keyboard, _ := entity.NewProduct("Keyboard", price)
+	novel, _ := entity.NewProduct("Novel", price)
+	assert.NoError(t, productDB.Create(keyboard))
+	assert.NoError(t, productDB.Create(novel))
+
+	assert.NoError(t, productDB.SetCategories(keyboard.ID.String(), []string{keyboards.ID.String()}))
+	assert.NoError(t, productDB.SetCategories(novel.ID.String(), []string{books.ID.String()}))
+	assert.Equal(t, entity.ErrCategoryNotFound, productDB.SetCategories(novel.ID.String(), []string{pkgEntity.NewID().String()}))
+
+	ids, err := categoryDB.Descendants(electronics.ID.String())
+	assert.NoError(t, err)
+
+	response, err := productDB.FindAll(1, perPage, "asc", ids...)
+	assert.NoError(t, err)
+	assert.Len(t, response.Products, 1)
+	assert.Equal(t, "Keyboard", response.Products[0].Name)
+	assert.Equal(t, "Keyboards", response.Products[0].Categories[0].Name)
+
+	found, err := productDB.FindById(novel.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, found.Categories, 1)
+	assert.Equal(t, novel.Version+1, found.Version)
+
+	books.Name = "Literature"
+	assert.NoError(t, categoryDB.Update(books))
+	found, err = productDB.FindById(novel.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, "Literature", found.Categories[0].Name)
+	assert.Equal(t, novel.Version+2, found.Version)
+
+	assert.NoError(t, categoryDB.Delete(books.ID.String()))
+	found, err = productDB.FindById(novel.ID.String())
+	assert.NoError(t, err)
+	assert.Empty(t, found.Categories)
+	assert.Equal(t, novel.Version+3, found.Version)
+}
@@ -0,0 +1,179 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	mysqlDriver "github.com/go-sql-driver/mysql"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+var ErrUnsupportedDriver = errors.New("unsupported database driver")
+
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+	DriverMySQL    = "mysql"
+)
+
+// Config describes how to reach the database and how to size its connection
+// pool. For SQLite, Name is the path of the database file (or ":memory:").
+type Config struct {
+	Driver          string
+	Host            string
+	Port            string
+	User            string
+	Password        string
+	Name            string
+	SSLMode         string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnectRetries  int
+	RetryInterval   time.Duration
+}
+
+// NormalizeDriver maps the accepted spellings of a driver name to one of the
+// Driver constants.
+func NormalizeDriver(driver string) (string, error) {
+	switch driver {
+	case "", "sqlite", "sqlite3":
+		return DriverSQLite, nil
+	case "postgres", "postgresql", "pgx":
+		return DriverPostgres, nil
+	case "mysql", "mariadb":
+		return DriverMySQL, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnsupportedDriver, driver)
+}
+
+// DSN builds the data source name of the configured driver.
+func DSN(cfg Config) (string, error) {
+	driver, err := NormalizeDriver(cfg.Driver)
+	if err != nil {
+		return "", err
+	}
+
+	switch driver {
+	case DriverPostgres:
+		sslMode := cfg.SSLMode
+		if sslMode == "" {
+			sslMode = "disable"
+		}
+		dsn := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(cfg.User, cfg.Password),
+			Host:     net.JoinHostPort(cfg.Host, defaultString(cfg.Port, "5432")),
+			Path:     "/" + cfg.Name,
+			RawQuery: url.Values{"sslmode": {sslMode}}.Encode(),
+		}
+		return dsn.String(), nil
+	case DriverMySQL:
+		dsn := mysqlDriver.NewConfig()
+		dsn.User = cfg.User
+		dsn.Passwd = cfg.Password
+		dsn.Net = "tcp"
+		dsn.Addr = net.JoinHostPort(cfg.Host, defaultString(cfg.Port, "3306"))
+		dsn.DBName = cfg.Name
+		dsn.ParseTime = true
+		dsn.Params = map[string]string{"charset": "utf8mb4"}
+		return dsn.FormatDSN(), nil
+	default:
+		return defaultString(cfg.Name, "test.db"), nil
+	}
+}
+
+// Dialector returns the gorm dialector of the configured driver.
+func Dialector(cfg Config) (gorm.Dialector, error) {
+	dsn, err := DSN(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	driver, _ := NormalizeDriver(cfg.Driver)
+	switch driver {
+	case DriverPostgres:
+		return postgres.Open(dsn), nil
+	case DriverMySQL:
+		return mysql.Open(dsn), nil
+	default:
+		return sqlite.Open(dsn), nil
+	}
+}
+
+// Open connects to the configured database, retrying with a doubling interval
+// while the server is not reachable yet (e.g. a database container that is
+// still starting), and applies the pool settings.
+func Open(cfg Config, gormConfig *gorm.Config) (*gorm.DB, error) {
+	dialector, err := Dialector(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	interval := cfg.RetryInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	var db *gorm.DB
+	for attempt := 0; ; attempt++ {
+		db, err = connect(dialector, gormConfig)
+		if err == nil || attempt >= cfg.ConnectRetries {
+			break
+		}
+		log.Printf("database not ready (attempt %d of %d): %v", attempt+1, cfg.ConnectRetries+1, err)
+		time.Sleep(interval)
+		interval *= 2
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+
+	return db, nil
+}
+
+func connect(dialector gorm.Dialector, gormConfig *gorm.Config) (*gorm.DB, error) {
+	db, err := gorm.Open(dialector, gormConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	if err = sqlDB.Ping(); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func defaultString(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,36 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestDSN(t *testing.T) {
+	dsn, err := DSN(Config{Driver: "sqlite3", Name: "file::memory:"})
+	assert.NoError(t, err)
+	assert.Equal(t, "file::memory:", dsn)
+
+	dsn, err = DSN(Config{Driver: "postgres", Host: "db", User: "app", Password: "p@ss word", Name: "products"})
+	assert.NoError(t, err)
+	assert.Equal(t, "postgres://app:p%40ss%20word@db:5432/products?sslmode=disable", dsn)
+
+	dsn, err = DSN(Config{Driver: "mysql", Host: "localhost", Port: "3307", User: "root", Password: "root", Name: "escapps"})
+	assert.NoError(t, err)
+	assert.Equal(t, "root:root@tcp(localhost:3307)/escapps?parseTime=true&charset=utf8mb4", dsn)
+
+	_, err = DSN(Config{Driver: "oracle"})
+	assert.True(t, errors.Is(err, ErrUnsupportedDriver))
+}
+
+func TestOpenSQLite(t *testing.T) {
+	db, err := Open(Config{Driver: "sqlite", Name: "file::memory:", MaxOpenConns: 1}, &gorm.Config{})
+	assert.NoError(t, err)
+
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sqlDB.Stats().MaxOpenConnections)
+	assert.Equal(t, "sqlite", db.Dialector.Name())
+}
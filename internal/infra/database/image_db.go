@@ -0,0 +1,164 @@
+package database
+
+import (
+	"context"
+
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	"gorm.io/gorm"
+)
+
+type Image struct {
+	DB *gorm.DB
+}
+
+func NewImage(db *gorm.DB) *Image {
+	return &Image{DB: db}
+}
+
+// WithContext returns the repository bound to ctx.
+func (i *Image) WithContext(ctx context.Context) ImageInterface {
+	return &Image{DB: i.DB.WithContext(ctx)}
+}
+
+func imageAudit(tx *gorm.DB, image *entity.ProductImage, action entity.AuditAction, before, after map[string]interface{}) error {
+	entry := entity.NewAuditEntry("image", image.ID, action, before, after)
+	entry.ProductID = &image.ProductID
+	return recordAudit(tx, entry)
+}
+
+// Create saves image as the last image of its product.
+func (i *Image) Create(image *entity.ProductImage) error {
+	return i.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("id").First(&entity.Product{}, "id = ?", image.ProductID).Error; err != nil {
+			return err
+		}
+
+		err := tx.Model(&entity.ProductImage{}).Where("product_id = ?", image.ProductID).
+			Select("COALESCE(MAX(position) + 1, 0)").Scan(&image.Position).Error
+		if err != nil {
+			return err
+		}
+
+		if err = tx.Create(image).Error; err != nil {
+			return err
+		}
+		if err = touchProduct(tx, image.ProductID.String()); err != nil {
+			return err
+		}
+		return imageAudit(tx, image, entity.AuditCreate, nil, image.AuditFields())
+	})
+}
+
+// FindByProduct lists the images of a product, in order.
+func (i *Image) FindByProduct(productID string) ([]entity.ProductImage, error) {
+	if err := i.DB.Select("id").First(&entity.Product{}, "id = ?", productID).Error; err != nil {
+		return nil, err
+	}
+
+	images := []entity.ProductImage{}
+	err := i.DB.Where("product_id = ?", productID).Order("position").Find(&images).Error
+	return images, err
+}
+
+func (i *Image) FindById(productID, id string) (*entity.ProductImage, error) {
+	var image entity.ProductImage
+	err := i.DB.First(&image, "id = ? AND product_id = ?", id, productID).Error
+	return &image, err
+}
+
+// Reorder puts the images of a product in the order of ids, which must list
+// each of them exactly once, and returns them in that order.
+func (i *Image) Reorder(productID string, ids []string) ([]entity.ProductImage, error) {
+	var images []entity.ProductImage
+	err := i.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("id").First(&entity.Product{}, "id = ?", productID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("product_id = ?", productID).Find(&images).Error; err != nil {
+			return err
+		}
+
+		positions := make(map[string]int, len(ids))
+		for position, id := range ids {
+			positions[id] = position
+		}
+		if len(ids) != len(images) || len(positions) != len(ids) {
+			return entity.ErrInvalidImageOrder
+		}
+		for _, image := range images {
+			if _, ok := positions[image.ID.String()]; !ok {
+				return entity.ErrInvalidImageOrder
+			}
+		}
+
+		moved := false
+		for k := range images {
+			position := positions[images[k].ID.String()]
+			if position == images[k].Position {
+				continue
+			}
+			before := images[k].AuditFields()
+			if err := tx.Model(&images[k]).Update("position", position).Error; err != nil {
+				return err
+			}
+			images[k].Position = position
+			moved = true
+			if err := imageAudit(tx, &images[k], entity.AuditUpdate, before, images[k].AuditFields()); err != nil {
+				return err
+			}
+		}
+		if !moved {
+			return nil
+		}
+		return touchProduct(tx, productID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ordered := make([]entity.ProductImage, len(images))
+	for _, image := range images {
+		ordered[image.Position] = image
+	}
+	return ordered, nil
+}
+
+// Delete removes the record of an image, closing the gap it leaves in the
+// order. Its files are left to the caller to delete from the storage.
+func (i *Image) Delete(productID, id string) error {
+	return i.DB.Transaction(func(tx *gorm.DB) error {
+		var image entity.ProductImage
+		if err := tx.First(&image, "id = ? AND product_id = ?", id, productID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&image).Error; err != nil {
+			return err
+		}
+
+		err := tx.Model(&entity.ProductImage{}).
+			Where("product_id = ? AND position > ?", productID, image.Position).
+			Update("position", gorm.Expr("position - 1")).Error
+		if err != nil {
+			return err
+		}
+		if err = touchProduct(tx, productID); err != nil {
+			return err
+		}
+		return imageAudit(tx, &image, entity.AuditDelete, image.AuditFields(), nil)
+	})
+}
+
+// PurgeOrphans removes the records of the images whose product was purged, and
+// returns them so that their files can be deleted too.
+func (i *Image) PurgeOrphans() ([]entity.ProductImage, error) {
+	var orphans []entity.ProductImage
+	err := i.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("product_id NOT IN (?)", tx.Unscoped().Model(&entity.Product{}).Select("id")).
+			Find(&orphans).Error
+		if err != nil || len(orphans) == 0 {
+			return err
+		}
+		return tx.Delete(&orphans).Error
+	})
+	return orphans, err
+}
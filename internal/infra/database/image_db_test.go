@@ -0,0 +1,81 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	pkgEntity "github.com/sallescosta/user-and-products-manager/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestImages(t *testing.T) {
+	db := NewTestDB(t)
+	productDB := NewProduct(db)
+	imageDB := NewImage(db)
+
+	product, _ := entity.NewProduct("Lamp", price)
+	assert.NoError(t, productDB.Create(product))
+
+	var images []*entity.ProductImage
+	for _, contentType := range []string{"image/jpeg", "image/png", "image/webp"} {
+		image, err := entity.NewProductImage(product.ID, contentType, 1024, 800, 600)
+		assert.NoError(t, err)
+		assert.NoError(t, imageDB.Create(image))
+		images = append(images, image)
+	}
+	assert.Equal(t, 2, images[2].Position)
+
+	// the images are part of the product, so they change its version and ETag
+	found, err := productDB.FindById(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, product.Version+3, found.Version)
+
+	orphan, _ := entity.NewProductImage(pkgEntity.NewID(), "image/png", 1024, 800, 600)
+	assert.ErrorIs(t, imageDB.Create(orphan), gorm.ErrRecordNotFound)
+
+	reordered, err := imageDB.Reorder(product.ID.String(), []string{images[2].ID.String(), images[0].ID.String(), images[1].ID.String()})
+	assert.NoError(t, err)
+	assert.Equal(t, images[2].ID, reordered[0].ID)
+	assert.Equal(t, images[1].ID, reordered[2].ID)
+	assert.Equal(t, images[1].Key, reordered[2].Key)
+
+	for _, ids := range [][]string{
+		{images[0].ID.String(), images[1].ID.String()},
+		{images[0].ID.String(), images[0].ID.String(), images[1].ID.String()},
+		{images[0].ID.String(), images[1].ID.String(), pkgEntity.NewID().String()},
+	} {
+		_, err = imageDB.Reorder(product.ID.String(), ids)
+		assert.ErrorIs(t, err, entity.ErrInvalidImageOrder)
+	}
+
+	// deleting an image closes the gap in the order
+	assert.NoError(t, imageDB.Delete(product.ID.String(), images[2].ID.String()))
+	found, err = productDB.FindById(product.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, found.Images, 2)
+	assert.Equal(t, images[0].ID, found.Images[0].ID)
+	assert.Equal(t, 0, found.Images[0].Position)
+	assert.Equal(t, 1, found.Images[1].Position)
+
+	_, err = imageDB.FindById(product.ID.String(), images[2].ID.String())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	// the images of trashed products are kept until the product is purged
+	product.Version = found.Version
+	assert.NoError(t, productDB.Delete(product.ID.String(), product.Version))
+	orphans, err := imageDB.PurgeOrphans()
+	assert.NoError(t, err)
+	assert.Empty(t, orphans)
+
+	_, err = productDB.Purge(time.Now().Add(time.Second))
+	assert.NoError(t, err)
+	orphans, err = imageDB.PurgeOrphans()
+	assert.NoError(t, err)
+	assert.Len(t, orphans, 2)
+
+	var left int64
+	db.Model(&entity.ProductImage{}).Count(&left)
+	assert.Zero(t, left)
+}
@@ -1,17 +1,113 @@
 package database
 
-import "github.com/sallescosta/user-and-products-manager/internal/entity"
+import (
+	"context"
+	"time"
+
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+)
 
 type UserInterface interface {
 	Create(user *entity.User) error
+	Register(user *entity.User) error
 	FindByEmail(email string) (*entity.User, error)
+	FindById(id string) (*entity.User, error)
+	Update(user *entity.User) error
+	Count() (int64, error)
 	GetAllUsers() ([]entity.User, error)
 }
 
+type RefreshTokenInterface interface {
+	Create(token *entity.RefreshToken) error
+	FindByHash(hash string) (*entity.RefreshToken, error)
+	Rotate(old, replacement *entity.RefreshToken) error
+	Revoke(id string) error
+	RevokeAllForUser(userID string) error
+}
+
+type PasswordResetInterface interface {
+	Create(token *entity.PasswordResetToken) error
+	FindByHash(hash string) (*entity.PasswordResetToken, error)
+	Consume(token *entity.PasswordResetToken, user *entity.User) error
+}
+
+// LoginAttemptInterface keeps the counters of failed logins, by key.
+type LoginAttemptInterface interface {
+	// Find returns the failures of key at now, none once they expired.
+	Find(key string, now time.Time) (entity.LoginAttempt, error)
+	// Fail counts a failure of key at now and returns its updated counter.
+	Fail(key string, now time.Time, limit entity.LoginLimit) (entity.LoginAttempt, error)
+	Reset(key string) error
+}
+
+type RevokedTokenInterface interface {
+	Revoke(jti string, expiresAt time.Time) error
+	IsRevoked(jti string) (bool, error)
+}
+
 type ProductInterface interface {
+	WithContext(ctx context.Context) ProductInterface
 	Create(product *entity.Product) error
+	CreateBatch(products []*entity.Product) error
 	FindById(id string) (*entity.Product, error)
 	Update(product *entity.Product) error
+	Delete(id string, version int) error
+	FindAll(page, limit int, sort string, categoryIDs ...string) (ProductResponse, error)
+	SetCategories(id string, categoryIDs []string) error
+	FindByQuery(q ProductQuery) (ProductResponse, error)
+	Export(q ProductQuery, fn func(product *entity.Product) error) error
+	Search(q string, page, limit int) (ProductResponse, error)
+	FindDeleted(page, limit int) (ProductResponse, error)
+	Restore(id string) error
+	Purge(before time.Time) (int64, error)
+}
+
+type CategoryInterface interface {
+	WithContext(ctx context.Context) CategoryInterface
+	Create(category *entity.Category) error
+	FindById(id string) (*entity.Category, error)
+	FindAll() ([]entity.Category, error)
+	Update(category *entity.Category) error
 	Delete(id string) error
-	FindAll(page, limit int, sort string) (ProductResponse, error)
+	Descendants(id string) ([]string, error)
+}
+
+type StockInterface interface {
+	WithContext(ctx context.Context) StockInterface
+	Record(movement *entity.StockMovement, allowNegative bool) error
+	OnHand(productID string) (int, error)
+	Movements(productID string, page, limit int) ([]entity.StockMovement, error)
+	VariantMovements(productID, variantID string, page, limit int) ([]entity.StockMovement, error)
+}
+
+type VariantInterface interface {
+	WithContext(ctx context.Context) VariantInterface
+	Create(variant *entity.Variant) error
+	FindByProduct(productID string) ([]entity.Variant, error)
+	FindById(productID, id string) (*entity.Variant, error)
+	Update(variant *entity.Variant) error
+	Delete(productID, id string) error
+}
+
+type ImageInterface interface {
+	WithContext(ctx context.Context) ImageInterface
+	Create(image *entity.ProductImage) error
+	FindByProduct(productID string) ([]entity.ProductImage, error)
+	FindById(productID, id string) (*entity.ProductImage, error)
+	Reorder(productID string, ids []string) ([]entity.ProductImage, error)
+	Delete(productID, id string) error
+	PurgeOrphans() ([]entity.ProductImage, error)
+}
+
+type PriceInterface interface {
+	WithContext(ctx context.Context) PriceInterface
+	Timeline(productID string) ([]entity.ProductPrice, error)
+	Schedule(price *entity.ProductPrice) error
+	Cancel(productID, id string) error
+	ApplyDue(now time.Time) (int64, error)
+}
+
+type AuditInterface interface {
+	FindByQuery(q AuditQuery) (AuditResponse, error)
+	ProductHistory(id string, page, limit int) (AuditResponse, error)
 }
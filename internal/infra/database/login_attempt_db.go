@@ -0,0 +1,118 @@
+package database
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginAttempt keeps the failed login counters in the database, shared by
+// every instance of the server.
+type LoginAttempt struct {
+	DB *gorm.DB
+}
+
+func NewLoginAttempt(db *gorm.DB) *LoginAttempt {
+	return &LoginAttempt{DB: db}
+}
+
+func (l *LoginAttempt) Find(key string, now time.Time) (entity.LoginAttempt, error) {
+	now = now.UTC()
+	var attempt entity.LoginAttempt
+	err := l.DB.Where("login_key = ? AND expires_at > ?", key, now).First(&attempt).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return entity.LoginAttempt{Key: key}, nil
+	}
+	return attempt, err
+}
+
+// Fail counts the failure with an increment in the database, so that
+// concurrent failures are all counted. Counters that expired, of every key,
+// are dropped on the way.
+func (l *LoginAttempt) Fail(key string, now time.Time, limit entity.LoginLimit) (entity.LoginAttempt, error) {
+	now = now.UTC()
+	var attempt entity.LoginAttempt
+	err := l.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at <= ?", now).Delete(&entity.LoginAttempt{}).Error; err != nil {
+			return err
+		}
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&entity.LoginAttempt{Key: key, ExpiresAt: now}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&entity.LoginAttempt{}).Where("login_key = ?", key).
+			Update("failures", gorm.Expr("failures + 1")).Error
+		if err != nil {
+			return err
+		}
+
+		if err = tx.First(&attempt, "login_key = ?", key).Error; err != nil {
+			return err
+		}
+		attempt.Block(now, limit)
+		return tx.Model(&entity.LoginAttempt{}).Where("login_key = ?", key).
+			Updates(map[string]interface{}{"blocked_until": attempt.BlockedUntil, "expires_at": attempt.ExpiresAt}).Error
+	})
+	return attempt, err
+}
+
+func (l *LoginAttempt) Reset(key string) error {
+	return l.DB.Where("login_key = ?", key).Delete(&entity.LoginAttempt{}).Error
+}
+
+// MemoryLoginAttempt keeps the failed login counters in memory, for a single
+// instance of the server. They are lost on restart.
+type MemoryLoginAttempt struct {
+	mu        sync.Mutex
+	attempts  map[string]entity.LoginAttempt
+	lastSweep time.Time
+}
+
+func NewMemoryLoginAttempt() *MemoryLoginAttempt {
+	return &MemoryLoginAttempt{attempts: map[string]entity.LoginAttempt{}}
+}
+
+func (m *MemoryLoginAttempt) Find(key string, now time.Time) (entity.LoginAttempt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	attempt, ok := m.attempts[key]
+	if !ok || !now.Before(attempt.ExpiresAt) {
+		return entity.LoginAttempt{Key: key}, nil
+	}
+	return attempt, nil
+}
+
+// Fail counts the failure. Once a minute at most, the counters that expired
+// are dropped on the way.
+func (m *MemoryLoginAttempt) Fail(key string, now time.Time, limit entity.LoginLimit) (entity.LoginAttempt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if now.Sub(m.lastSweep) >= time.Minute {
+		for k, attempt := range m.attempts {
+			if !now.Before(attempt.ExpiresAt) {
+				delete(m.attempts, k)
+			}
+		}
+		m.lastSweep = now
+	}
+
+	attempt := m.attempts[key]
+	attempt.Key = key
+	attempt.Fail(now, limit)
+	m.attempts[key] = attempt
+	return attempt, nil
+}
+
+func (m *MemoryLoginAttempt) Reset(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.attempts, key)
+	return nil
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func testLoginAttemptStore(t *testing.T, store LoginAttemptInterface) {
+	limit := entity.LoginLimit{MaxFailures: 3, Backoff: time.Second, Lockout: time.Minute}
+	now := time.Now().UTC()
+
+	attempt, err := store.Find("account:john@gmail.com", now)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, attempt.Failures)
+	assert.Equal(t, time.Duration(0), attempt.RetryAfter(now))
+
+	for i := 1; i <= 3; i++ {
+		attempt, err = store.Fail("account:john@gmail.com", now, limit)
+		assert.NoError(t, err)
+		assert.Equal(t, i, attempt.Failures)
+	}
+	_, err = store.Fail("ip:192.0.2.1", now, limit)
+	assert.NoError(t, err)
+
+	attempt, err = store.Find("account:john@gmail.com", now)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempt.Failures)
+	assert.Equal(t, time.Minute, attempt.RetryAfter(now))
+
+	assert.NoError(t, store.Reset("account:john@gmail.com"))
+	attempt, err = store.Find("account:john@gmail.com", now)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, attempt.Failures)
+
+	// expired counters start over
+	later := now.Add(2 * time.Minute)
+	attempt, err = store.Find("ip:192.0.2.1", later)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, attempt.Failures)
+	attempt, err = store.Fail("ip:192.0.2.1", later, limit)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, attempt.Failures)
+	assert.Equal(t, time.Second, attempt.RetryAfter(later))
+}
+
+func TestLoginAttempt(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	assert.NoError(t, db.AutoMigrate(&entity.LoginAttempt{}))
+
+	testLoginAttemptStore(t, NewLoginAttempt(db))
+}
+
+func TestMemoryLoginAttempt(t *testing.T) {
+	testLoginAttemptStore(t, NewMemoryLoginAttempt())
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type productV1 struct {
+	ID        string `gorm:"size:36;primaryKey"`
+	Name      string `gorm:"size:255"`
+	Price     float64
+	CreatedAt time.Time
+}
+
+func (productV1) TableName() string {
+	return "products"
+}
+
+func init() {
+	register(Migration{
+		Version: 1,
+		Name:    "create_products",
+		Up: func(tx *gorm.DB) error {
+			return createTable(tx, &productV1{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&productV1{})
+		},
+	})
+}
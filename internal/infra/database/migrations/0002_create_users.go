@@ -0,0 +1,28 @@
+package migrations
+
+import "gorm.io/gorm"
+
+type userV2 struct {
+	ID       string `gorm:"size:36;primaryKey"`
+	Name     string `gorm:"size:255"`
+	Email    string `gorm:"size:255"`
+	Password string `gorm:"size:255"`
+	Role     string `gorm:"size:16;not null;default:viewer"`
+}
+
+func (userV2) TableName() string {
+	return "users"
+}
+
+func init() {
+	register(Migration{
+		Version: 2,
+		Name:    "create_users",
+		Up: func(tx *gorm.DB) error {
+			return createTable(tx, &userV2{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&userV2{})
+		},
+	})
+}
@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type refreshTokenV3 struct {
+	ID        string `gorm:"size:36;primaryKey"`
+	UserID    string `gorm:"size:36;index"`
+	TokenHash string `gorm:"size:64;uniqueIndex"`
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+func (refreshTokenV3) TableName() string {
+	return "refresh_tokens"
+}
+
+type revokedTokenV3 struct {
+	JTI       string    `gorm:"size:36;primaryKey"`
+	ExpiresAt time.Time `gorm:"index"`
+}
+
+func (revokedTokenV3) TableName() string {
+	return "revoked_tokens"
+}
+
+func init() {
+	register(Migration{
+		Version: 3,
+		Name:    "create_refresh_tokens",
+		Up: func(tx *gorm.DB) error {
+			if err := createTable(tx, &refreshTokenV3{}); err != nil {
+				return err
+			}
+			return createTable(tx, &revokedTokenV3{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&revokedTokenV3{}, &refreshTokenV3{})
+		},
+	})
+}
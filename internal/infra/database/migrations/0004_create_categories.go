@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type categoryV4 struct {
+	ID        string  `gorm:"size:36;primaryKey"`
+	Name      string  `gorm:"size:255"`
+	ParentID  *string `gorm:"size:36;index"`
+	CreatedAt time.Time
+}
+
+func (categoryV4) TableName() string {
+	return "categories"
+}
+
+type productCategoryV4 struct {
+	ProductID  string `gorm:"size:36;primaryKey"`
+	CategoryID string `gorm:"size:36;primaryKey;index"`
+}
+
+func (productCategoryV4) TableName() string {
+	return "product_categories"
+}
+
+func init() {
+	register(Migration{
+		Version: 4,
+		Name:    "create_categories",
+		Up: func(tx *gorm.DB) error {
+			if err := createTable(tx, &categoryV4{}); err != nil {
+				return err
+			}
+			return createTable(tx, &productCategoryV4{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&productCategoryV4{}, &categoryV4{})
+		},
+	})
+}
@@ -0,0 +1,51 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type productV5 struct {
+	Quantity int `gorm:"not null;default:0"`
+}
+
+func (productV5) TableName() string {
+	return "products"
+}
+
+type stockMovementV5 struct {
+	ID        string `gorm:"size:36;primaryKey"`
+	ProductID string `gorm:"size:36;index"`
+	Type      string `gorm:"size:16"`
+	Quantity  int
+	Balance   int
+	Reason    string `gorm:"size:255"`
+	UserID    string `gorm:"size:36"`
+	CreatedAt time.Time
+}
+
+func (stockMovementV5) TableName() string {
+	return "stock_movements"
+}
+
+func init() {
+	register(Migration{
+		Version: 5,
+		Name:    "create_stock_movements",
+		Up: func(tx *gorm.DB) error {
+			if !tx.Migrator().HasColumn(&productV5{}, "quantity") {
+				if err := tx.Migrator().AddColumn(&productV5{}, "Quantity"); err != nil {
+					return err
+				}
+			}
+			return createTable(tx, &stockMovementV5{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&stockMovementV5{}); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&productV5{}, "quantity")
+		},
+	})
+}
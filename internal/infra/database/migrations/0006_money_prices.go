@@ -0,0 +1,59 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// productV6 replaces the float price of products with an integer amount of
+// minor units and an ISO 4217 currency.
+type productV6 struct {
+	PriceAmount   int64  `gorm:"not null;default:0"`
+	PriceCurrency string `gorm:"size:3;not null;default:'USD'"`
+	Price         float64
+}
+
+func (productV6) TableName() string {
+	return "products"
+}
+
+func init() {
+	register(Migration{
+		Version: 6,
+		Name:    "money_prices",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			for _, field := range []string{"PriceAmount", "PriceCurrency"} {
+				if m.HasColumn(&productV6{}, field) {
+					continue
+				}
+				if err := m.AddColumn(&productV6{}, field); err != nil {
+					return err
+				}
+			}
+
+			if !m.HasColumn(&productV6{}, "price") {
+				return nil
+			}
+			// every price stored so far was in dollars, with cents
+			err := tx.Exec("UPDATE products SET price_amount = ROUND(price * 100), price_currency = 'USD'").Error
+			if err != nil {
+				return err
+			}
+			return m.DropColumn(&productV6{}, "price")
+		},
+		Down: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if err := m.AddColumn(&productV6{}, "Price"); err != nil {
+				return err
+			}
+			// prices in currencies without cents cannot be told apart anymore
+			if err := tx.Exec("UPDATE products SET price = price_amount / 100.0").Error; err != nil {
+				return err
+			}
+			if err := m.DropColumn(&productV6{}, "price_currency"); err != nil {
+				return err
+			}
+			return m.DropColumn(&productV6{}, "price_amount")
+		},
+	})
+}
@@ -0,0 +1,84 @@
+package migrations
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// The product search index depends on the backend: an FTS5 table kept in sync
+// by triggers on SQLite, a GIN index over the name tsvector on PostgreSQL and
+// a FULLTEXT index on MySQL. The SQLite driver bundles FTS5, so a SQLite
+// without it is a setup error rather than something to skip: recording the
+// migration as applied would leave the index missing for good.
+var productSearchUp = map[string][]string{
+	"sqlite": {
+		"CREATE VIRTUAL TABLE products_fts USING fts5(id UNINDEXED, name)",
+		"INSERT INTO products_fts (id, name) SELECT id, name FROM products",
+		`CREATE TRIGGER products_fts_insert AFTER INSERT ON products BEGIN
+			INSERT INTO products_fts (id, name) VALUES (new.id, new.name);
+		END`,
+		`CREATE TRIGGER products_fts_update AFTER UPDATE OF name ON products BEGIN
+			UPDATE products_fts SET name = new.name WHERE id = old.id;
+		END`,
+		`CREATE TRIGGER products_fts_delete AFTER DELETE ON products BEGIN
+			DELETE FROM products_fts WHERE id = old.id;
+		END`,
+	},
+	"postgres": {
+		"CREATE INDEX idx_products_name_fts ON products USING GIN (to_tsvector('simple', name))",
+	},
+	"mysql": {
+		"CREATE FULLTEXT INDEX idx_products_name_fts ON products (name)",
+	},
+}
+
+var errNoFTS5 = errors.New("migrations: product_search needs SQLite with FTS5")
+
+var productSearchDown = map[string][]string{
+	"sqlite": {
+		"DROP TRIGGER IF EXISTS products_fts_delete",
+		"DROP TRIGGER IF EXISTS products_fts_update",
+		"DROP TRIGGER IF EXISTS products_fts_insert",
+		"DROP TABLE IF EXISTS products_fts",
+	},
+	"postgres": {
+		"DROP INDEX IF EXISTS idx_products_name_fts",
+	},
+	"mysql": {
+		"DROP INDEX idx_products_name_fts ON products",
+	},
+}
+
+func hasFTS5(tx *gorm.DB) (bool, error) {
+	var enabled bool
+	err := tx.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled).Error
+	return enabled, err
+}
+
+func init() {
+	register(Migration{
+		Version: 7,
+		Name:    "product_search",
+		Up: func(tx *gorm.DB) error {
+			dialect := tx.Dialector.Name()
+			if dialect == "sqlite" {
+				enabled, err := hasFTS5(tx)
+				if err != nil {
+					return err
+				}
+				if !enabled {
+					return errNoFTS5
+				}
+			}
+			return execAll(tx, productSearchUp[dialect])
+		},
+		Down: func(tx *gorm.DB) error {
+			dialect := tx.Dialector.Name()
+			if dialect == "mysql" && !tx.Migrator().HasIndex("products", "idx_products_name_fts") {
+				return nil
+			}
+			return execAll(tx, productSearchDown[dialect])
+		},
+	})
+}
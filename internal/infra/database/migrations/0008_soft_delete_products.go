@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type productV8 struct {
+	DeletedAt *time.Time `gorm:"index"`
+}
+
+func (productV8) TableName() string {
+	return "products"
+}
+
+func init() {
+	register(Migration{
+		Version: 8,
+		Name:    "soft_delete_products",
+		Up: func(tx *gorm.DB) error {
+			return createTable(tx, &productV8{})
+		},
+		Down: func(tx *gorm.DB) error {
+			// trashed products would come back to life, so they go for good
+			if err := tx.Exec("DELETE FROM product_categories WHERE product_id IN (SELECT id FROM products WHERE deleted_at IS NOT NULL)").Error; err != nil {
+				return err
+			}
+			if err := tx.Exec("DELETE FROM products WHERE deleted_at IS NOT NULL").Error; err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropIndex(&productV8{}, "idx_products_deleted_at"); err != nil {
+				return err
+			}
+			// the migrator drops SQLite columns by rebuilding the table, which
+			// would lose the search triggers
+			return tx.Exec("ALTER TABLE products DROP COLUMN deleted_at").Error
+		},
+	})
+}
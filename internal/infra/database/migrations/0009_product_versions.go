@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+type productV9 struct {
+	Version int `gorm:"not null;default:1"`
+}
+
+func (productV9) TableName() string {
+	return "products"
+}
+
+func init() {
+	register(Migration{
+		Version: 9,
+		Name:    "product_versions",
+		Up: func(tx *gorm.DB) error {
+			return createTable(tx, &productV9{})
+		},
+		Down: func(tx *gorm.DB) error {
+			// see soft_delete_products for why this is not the migrator
+			return tx.Exec("ALTER TABLE products DROP COLUMN version").Error
+		},
+	})
+}
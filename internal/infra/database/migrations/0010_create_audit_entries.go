@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type auditEntryV10 struct {
+	ID         string    `gorm:"size:36;primaryKey"`
+	EntityType string    `gorm:"size:32;index:idx_audit_entity"`
+	EntityID   string    `gorm:"size:36;index:idx_audit_entity"`
+	Action     string    `gorm:"size:16"`
+	ActorID    string    `gorm:"size:36;index"`
+	RequestID  string    `gorm:"size:64"`
+	Changes    string    `gorm:"type:text"`
+	CreatedAt  time.Time `gorm:"index"`
+}
+
+func (auditEntryV10) TableName() string {
+	return "audit_entries"
+}
+
+func init() {
+	register(Migration{
+		Version: 10,
+		Name:    "create_audit_entries",
+		Up: func(tx *gorm.DB) error {
+			return createTable(tx, &auditEntryV10{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&auditEntryV10{})
+		},
+	})
+}
@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type productPriceV11 struct {
+	ID            string `gorm:"size:36;primaryKey"`
+	ProductID     string `gorm:"size:36;index"`
+	PriceAmount   int64
+	PriceCurrency string    `gorm:"size:3"`
+	EffectiveFrom time.Time `gorm:"index"`
+	EffectiveTo   *time.Time
+	AppliedAt     *time.Time
+	UserID        string `gorm:"size:36"`
+	CreatedAt     time.Time
+}
+
+func (productPriceV11) TableName() string {
+	return "product_prices"
+}
+
+func init() {
+	register(Migration{
+		Version: 11,
+		Name:    "create_product_prices",
+		Up: func(tx *gorm.DB) error {
+			if err := createTable(tx, &productPriceV11{}); err != nil {
+				return err
+			}
+			// the timeline of existing products starts with their current price,
+			// in a period that takes the ID of the product, and that starts when
+			// the product was created, if known
+			return tx.Exec(`INSERT INTO product_prices (id, product_id, price_amount, price_currency, effective_from, applied_at, user_id, created_at)
+				SELECT id, id, price_amount, price_currency, since, since, '', since
+				FROM (SELECT id, price_amount, price_currency, COALESCE(created_at, CURRENT_TIMESTAMP) AS since FROM products) AS p
+				WHERE id NOT IN (SELECT product_id FROM product_prices)`).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&productPriceV11{})
+		},
+	})
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type variantV12 struct {
+	ID            string `gorm:"size:36;primaryKey"`
+	ProductID     string `gorm:"size:36;index"`
+	SKU           string `gorm:"size:64;uniqueIndex"`
+	Attributes    string `gorm:"type:text"`
+	PriceAmount   *int64
+	PriceCurrency *string `gorm:"size:3"`
+	Quantity      int     `gorm:"not null;default:0"`
+	CreatedAt     time.Time
+}
+
+func (variantV12) TableName() string {
+	return "variants"
+}
+
+func init() {
+	register(Migration{
+		Version: 12,
+		Name:    "create_variants",
+		Up: func(tx *gorm.DB) error {
+			return createTable(tx, &variantV12{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&variantV12{})
+		},
+	})
+}
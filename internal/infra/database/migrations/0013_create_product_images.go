@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type productImageV13 struct {
+	ID           string `gorm:"size:36;primaryKey"`
+	ProductID    string `gorm:"size:36;index"`
+	Key          string `gorm:"size:255"`
+	ThumbnailKey string `gorm:"size:255"`
+	ContentType  string `gorm:"size:32"`
+	Size         int64
+	Width        int
+	Height       int
+	Position     int `gorm:"not null;default:0"`
+	CreatedAt    time.Time
+}
+
+func (productImageV13) TableName() string {
+	return "product_images"
+}
+
+func init() {
+	register(Migration{
+		Version: 13,
+		Name:    "create_product_images",
+		Up: func(tx *gorm.DB) error {
+			return createTable(tx, &productImageV13{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&productImageV13{})
+		},
+	})
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+type userV15 struct {
+	Verified bool `gorm:"not null;default:false"`
+}
+
+func (userV15) TableName() string {
+	return "users"
+}
+
+func init() {
+	register(Migration{
+		Version: 15,
+		Name:    "user_verification",
+		Up: func(tx *gorm.DB) error {
+			if err := createTable(tx, &userV15{}); err != nil {
+				return err
+			}
+			// users registered before emails were verified are not asked to
+			return tx.Exec("UPDATE users SET verified = ?", true).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			// see soft_delete_products for why this is not the migrator
+			return tx.Exec("ALTER TABLE users DROP COLUMN verified").Error
+		},
+	})
+}
@@ -0,0 +1,63 @@
+package migrations
+
+import (
+	"log"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type userV16 struct {
+	Email string `gorm:"size:255;uniqueIndex"`
+}
+
+func (userV16) TableName() string {
+	return "users"
+}
+
+// duplicateEmailDomain is where the emails of the duplicate accounts are moved
+// to. The .invalid top level domain never resolves.
+const duplicateEmailDomain = "duplicate.invalid"
+
+func init() {
+	register(Migration{
+		Version: 16,
+		Name:    "unique_user_emails",
+		Up: func(tx *gorm.DB) error {
+			var users []struct {
+				ID    string
+				Email string
+			}
+			if err := tx.Raw("SELECT id, email FROM users ORDER BY id").Scan(&users).Error; err != nil {
+				return err
+			}
+
+			// emails are stored trimmed and in lower case from now on. Of the
+			// accounts that then share one, the first by ID keeps it, being
+			// the one logins found until now; the others get a placeholder
+			// for an admin to sort out. Only IDs are logged, not the emails
+			owners := map[string]string{}
+			for _, user := range users {
+				email := strings.ToLower(strings.TrimSpace(user.Email))
+				if owner, taken := owners[email]; taken {
+					email = user.ID + "@" + duplicateEmailDomain
+					log.Printf("migration unique_user_emails: user %s shares the email of user %s, moved to %s", user.ID, owner, email)
+				}
+				owners[email] = user.ID
+
+				if email == user.Email {
+					continue
+				}
+				if err := tx.Exec("UPDATE users SET email = ? WHERE id = ?", email, user.ID).Error; err != nil {
+					return err
+				}
+			}
+
+			return createTable(tx, &userV16{})
+		},
+		Down: func(tx *gorm.DB) error {
+			// the emails stay normalized
+			return tx.Migrator().DropIndex(&userV16{}, "idx_users_email")
+		},
+	})
+}
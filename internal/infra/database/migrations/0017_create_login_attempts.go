@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type loginAttemptV17 struct {
+	Key          string `gorm:"column:login_key;size:320;primaryKey"`
+	Failures     int
+	BlockedUntil time.Time
+	ExpiresAt    time.Time `gorm:"index"`
+}
+
+func (loginAttemptV17) TableName() string {
+	return "login_attempts"
+}
+
+func init() {
+	register(Migration{
+		Version: 17,
+		Name:    "create_login_attempts",
+		Up: func(tx *gorm.DB) error {
+			return createTable(tx, &loginAttemptV17{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&loginAttemptV17{})
+		},
+	})
+}
@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+type auditEntryV18 struct {
+	ProductID *string `gorm:"size:36;index"`
+}
+
+func (auditEntryV18) TableName() string {
+	return "audit_entries"
+}
+
+// The entries of variants and images are linked to their product through the
+// rows still there; those of variants and images deleted since keep no
+// product, as nothing tells which one it was.
+var auditProductIDs = []string{
+	"UPDATE audit_entries SET product_id = entity_id WHERE entity_type = 'product'",
+	"UPDATE audit_entries SET product_id = (SELECT product_id FROM variants WHERE variants.id = audit_entries.entity_id) WHERE entity_type = 'variant'",
+	"UPDATE audit_entries SET product_id = (SELECT product_id FROM product_images WHERE product_images.id = audit_entries.entity_id) WHERE entity_type = 'image'",
+}
+
+func init() {
+	register(Migration{
+		Version: 18,
+		Name:    "audit_product_ids",
+		Up: func(tx *gorm.DB) error {
+			if err := createTable(tx, &auditEntryV18{}); err != nil {
+				return err
+			}
+			return execAll(tx, auditProductIDs)
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&auditEntryV18{}, "idx_audit_entries_product_id"); err != nil {
+				return err
+			}
+			// see soft_delete_products for why this is not the migrator
+			return tx.Exec("ALTER TABLE audit_entries DROP COLUMN product_id").Error
+		},
+	})
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+type stockMovementV19 struct {
+	VariantID *string `gorm:"size:36;index"`
+}
+
+func (stockMovementV19) TableName() string {
+	return "stock_movements"
+}
+
+func init() {
+	register(Migration{
+		Version: 19,
+		Name:    "stock_movement_variants",
+		Up: func(tx *gorm.DB) error {
+			return createTable(tx, &stockMovementV19{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&stockMovementV19{}, "idx_stock_movements_variant_id"); err != nil {
+				return err
+			}
+			// see soft_delete_products for why this is not the migrator
+			return tx.Exec("ALTER TABLE stock_movements DROP COLUMN variant_id").Error
+		},
+	})
+}
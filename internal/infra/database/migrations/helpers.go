@@ -0,0 +1,48 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// createTable creates the table of model. Databases created before migrations
+// existed (through AutoMigrate) already have it, possibly missing columns or
+// indexes added since, so in that case only what is missing is added.
+func createTable(tx *gorm.DB, model interface{}) error {
+	m := tx.Migrator()
+	if !m.HasTable(model) {
+		return m.CreateTable(model)
+	}
+
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(model); err != nil {
+		return err
+	}
+
+	for _, field := range stmt.Schema.Fields {
+		if field.DBName == "" || m.HasColumn(model, field.DBName) {
+			continue
+		}
+		if err := m.AddColumn(model, field.DBName); err != nil {
+			return err
+		}
+	}
+
+	for _, index := range stmt.Schema.ParseIndexes() {
+		if m.HasIndex(model, index.Name) {
+			continue
+		}
+		if err := m.CreateIndex(model, index.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// execAll runs the raw statements in order.
+func execAll(tx *gorm.DB, statements []string) error {
+	for _, sql := range statements {
+		if err := tx.Exec(sql).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
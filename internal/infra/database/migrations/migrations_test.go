@@ -0,0 +1,179 @@
+package migrations
+
+import (
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every connection to :memory: is a different database
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	return db
+}
+
+func TestAllIsOrdered(t *testing.T) {
+	all := All()
+	assert.NotEmpty(t, all)
+	for i := 1; i < len(all); i++ {
+		assert.Less(t, all[i-1].Version, all[i].Version)
+	}
+}
+
+func TestUpAndDown(t *testing.T) {
+	db := newTestDB(t)
+
+	ran, err := Up(db)
+	assert.NoError(t, err)
+	assert.Len(t, ran, len(All()))
+	assert.True(t, db.Migrator().HasTable("products"))
+	assert.True(t, db.Migrator().HasTable("users"))
+	assert.True(t, db.Migrator().HasTable("products_fts"))
+
+	ran, err = Up(db)
+	assert.NoError(t, err)
+	assert.Empty(t, ran)
+
+	status, err := GetStatus(db)
+	assert.NoError(t, err)
+	for _, s := range status {
+		assert.True(t, s.Applied)
+		assert.NotNil(t, s.AppliedAt)
+	}
+
+	ran, err = Down(db, len(All()))
+	assert.NoError(t, err)
+	assert.Len(t, ran, len(All()))
+	assert.Equal(t, 1, ran[len(ran)-1].Version)
+	assert.False(t, db.Migrator().HasTable("products"))
+	assert.False(t, db.Migrator().HasTable("users"))
+
+	_, err = Down(db, 1)
+	assert.Equal(t, ErrNothingToRollback, err)
+}
+
+func TestUpAdoptsExistingTables(t *testing.T) {
+	db := newTestDB(t)
+
+	// schema left behind by the AutoMigrate calls that predate migrations
+	assert.NoError(t, db.Exec("CREATE TABLE `products` (`id` text,`name` text,`price` real,`created_at` datetime,PRIMARY KEY (`id`))").Error)
+	assert.NoError(t, db.Exec("CREATE TABLE `users` (`id` text,`name` text,`email` text,`password` text,PRIMARY KEY (`id`))").Error)
+	assert.NoError(t, db.Exec("INSERT INTO `users` (`id`, `name`, `email`, `password`) VALUES ('1', 'John', 'john@gmail.com', 'x')").Error)
+
+	_, err := Up(db)
+	assert.NoError(t, err)
+	assert.True(t, db.Migrator().HasColumn("users", "role"))
+
+	var role string
+	assert.NoError(t, db.Raw("SELECT role FROM users WHERE id = '1'").Scan(&role).Error)
+	assert.Equal(t, "viewer", role)
+
+	var verified bool
+	assert.NoError(t, db.Raw("SELECT verified FROM users WHERE id = '1'").Scan(&verified).Error)
+	assert.True(t, verified)
+}
+
+func TestMoneyPricesMigration(t *testing.T) {
+	db := newTestDB(t)
+
+	assert.NoError(t, db.Exec("CREATE TABLE `products` (`id` text,`name` text,`price` real,`created_at` datetime,PRIMARY KEY (`id`))").Error)
+	assert.NoError(t, db.Exec("INSERT INTO `products` (`id`, `name`, `price`) VALUES ('1', 'Book', 10.35)").Error)
+
+	_, err := Up(db)
+	assert.NoError(t, err)
+	assert.False(t, db.Migrator().HasColumn("products", "price"))
+
+	var price struct {
+		PriceAmount   int64
+		PriceCurrency string
+	}
+	assert.NoError(t, db.Raw("SELECT price_amount, price_currency FROM products WHERE id = '1'").Scan(&price).Error)
+	assert.Equal(t, int64(1035), price.PriceAmount)
+	assert.Equal(t, "USD", price.PriceCurrency)
+
+	// the price timeline of existing products starts with their price
+	var periods []struct {
+		ProductID   string
+		PriceAmount int64
+	}
+	assert.NoError(t, db.Raw("SELECT product_id, price_amount FROM product_prices WHERE applied_at IS NOT NULL AND effective_to IS NULL").Scan(&periods).Error)
+	assert.Len(t, periods, 1)
+	assert.Equal(t, "1", periods[0].ProductID)
+	assert.Equal(t, int64(1035), periods[0].PriceAmount)
+
+	// roll back to right before the money_prices migration
+	_, err = Down(db, len(All())-5)
+	assert.NoError(t, err)
+	assert.False(t, db.Migrator().HasColumn("products", "price_amount"))
+
+	var legacy float64
+	assert.NoError(t, db.Raw("SELECT price FROM products WHERE id = '1'").Scan(&legacy).Error)
+	assert.Equal(t, 10.35, legacy)
+}
+
+func TestUniqueUserEmailsMigration(t *testing.T) {
+	db := newTestDB(t)
+
+	assert.NoError(t, db.Exec("CREATE TABLE `users` (`id` text,`name` text,`email` text,`password` text,PRIMARY KEY (`id`))").Error)
+	assert.NoError(t, db.Exec("INSERT INTO `users` (`id`, `name`, `email`, `password`) VALUES ('1', 'John', ' John@Gmail.com', 'x'), ('2', 'Jane', 'jane@gmail.com', 'x'), ('3', 'John again', 'john@gmail.com', 'x')").Error)
+
+	_, err := Up(db)
+	assert.NoError(t, err)
+
+	var emails []string
+	assert.NoError(t, db.Raw("SELECT email FROM users ORDER BY id").Scan(&emails).Error)
+	assert.Equal(t, []string{"john@gmail.com", "jane@gmail.com", "3@duplicate.invalid"}, emails)
+
+	assert.Error(t, db.Exec("INSERT INTO `users` (`id`, `name`, `email`, `password`) VALUES ('4', 'Jane again', 'jane@gmail.com', 'x')").Error)
+}
+
+func TestAuditProductIDsMigration(t *testing.T) {
+	db := newTestDB(t)
+
+	_, err := Up(db)
+	assert.NoError(t, err)
+	_, err = Down(db, len(All())-17)
+	assert.NoError(t, err)
+
+	assert.NoError(t, db.Exec("INSERT INTO `variants` (`id`, `product_id`, `sku`) VALUES ('v1', 'p1', 'SKU-1')").Error)
+	assert.NoError(t, db.Exec("INSERT INTO `audit_entries` (`id`, `entity_type`, `entity_id`, `action`) VALUES ('1', 'product', 'p1', 'create'), ('2', 'variant', 'v1', 'create'), ('3', 'variant', 'v2', 'delete')").Error)
+
+	_, err = Up(db)
+	assert.NoError(t, err)
+
+	var entries []struct {
+		ID        string
+		ProductID *string
+	}
+	assert.NoError(t, db.Raw("SELECT id, product_id FROM audit_entries ORDER BY id").Scan(&entries).Error)
+	assert.Len(t, entries, 3)
+	assert.Equal(t, "p1", *entries[0].ProductID)
+	assert.Equal(t, "p1", *entries[1].ProductID)
+	// the variant is gone, and its product with it
+	assert.Nil(t, entries[2].ProductID)
+}
+
+func TestStockMovementVariantsMigration(t *testing.T) {
+	db := newTestDB(t)
+
+	_, err := Up(db)
+	assert.NoError(t, err)
+	assert.True(t, db.Migrator().HasColumn(&stockMovementV19{}, "variant_id"))
+
+	_, err = Down(db, len(All())-18)
+	assert.NoError(t, err)
+	assert.False(t, db.Migrator().HasColumn(&stockMovementV19{}, "variant_id"))
+	assert.True(t, db.Migrator().HasTable("stock_movements"))
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"time"
+
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	"gorm.io/gorm"
+)
+
+type PasswordReset struct {
+	DB *gorm.DB
+}
+
+func NewPasswordReset(db *gorm.DB) *PasswordReset {
+	return &PasswordReset{DB: db}
+}
+
+// Create stores the token in place of the ones the user asked for earlier,
+// so that only the latest email works. Expired tokens of every user are
+// dropped on the way.
+func (p *PasswordReset) Create(token *entity.PasswordResetToken) error {
+	return p.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ? OR expires_at < ?", token.UserID, time.Now()).
+			Delete(&entity.PasswordResetToken{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	})
+}
+
+func (p *PasswordReset) FindByHash(hash string) (*entity.PasswordResetToken, error) {
+	var token entity.PasswordResetToken
+
+	err := p.DB.Where("token_hash = ?", hash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// Consume marks the token used and saves the new password and verified flag
+// of its user in one transaction, revoking the refresh tokens of the user as
+// well: whoever knew the old password can no longer renew their access
+// tokens, which run until they expire. It fails with
+// entity.ErrResetTokenUsed when the token was used concurrently.
+func (p *PasswordReset) Consume(token *entity.PasswordResetToken, user *entity.User) error {
+	return p.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&entity.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL", token.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return entity.ErrResetTokenUsed
+		}
+
+		err := tx.Model(&entity.User{}).Where("id = ?", user.ID).
+			Updates(map[string]interface{}{"password": user.Password, "verified": user.Verified}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&entity.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", user.ID).
+			Update("revoked_at", now).Error
+	})
+}
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestPasswordReset(t *testing.T) {
+	db := newTokenTestDB(t)
+	assert.NoError(t, db.AutoMigrate(&entity.User{}, &entity.PasswordResetToken{}))
+	resetDB := NewPasswordReset(db)
+	tokenDB := NewRefreshToken(db)
+
+	user, _ := entity.NewUser("John", "john@gmail.com", "123456")
+	assert.NoError(t, NewUser(db).Create(user))
+	session, sessionPlain, _ := entity.NewRefreshToken(user.ID, time.Hour)
+	assert.NoError(t, tokenDB.Create(session))
+
+	first, firstPlain, _ := entity.NewPasswordResetToken(user.ID, time.Hour)
+	assert.NoError(t, resetDB.Create(first))
+	second, secondPlain, _ := entity.NewPasswordResetToken(user.ID, time.Hour)
+	assert.NoError(t, resetDB.Create(second))
+
+	// asking again replaces the earlier token
+	_, err := resetDB.FindByHash(entity.HashToken(firstPlain))
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	found, err := resetDB.FindByHash(entity.HashToken(secondPlain))
+	assert.NoError(t, err)
+	assert.Equal(t, second.ID, found.ID)
+
+	assert.NoError(t, user.SetPassword("654321"))
+	user.Verified = true
+	assert.NoError(t, resetDB.Consume(found, user))
+	assert.Equal(t, entity.ErrResetTokenUsed, resetDB.Consume(found, user))
+
+	found, err = resetDB.FindByHash(entity.HashToken(secondPlain))
+	assert.NoError(t, err)
+	assert.Equal(t, entity.ErrResetTokenUsed, found.Validate(time.Now()))
+
+	stored, err := NewUser(db).FindById(user.ID.String())
+	assert.NoError(t, err)
+	assert.True(t, stored.ValidatePassword("654321"))
+	assert.True(t, stored.Verified)
+
+	session, err = tokenDB.FindByHash(entity.HashToken(sessionPlain))
+	assert.NoError(t, err)
+	assert.Equal(t, entity.ErrRefreshTokenRevoked, session.Validate(time.Now()))
+}
@@ -0,0 +1,191 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	entityPkg "github.com/sallescosta/user-and-products-manager/pkg/entity"
+	"gorm.io/gorm"
+)
+
+type Price struct {
+	DB *gorm.DB
+}
+
+func NewPrice(db *gorm.DB) *Price {
+	return &Price{DB: db}
+}
+
+// WithContext returns the repository bound to ctx.
+func (p *Price) WithContext(ctx context.Context) PriceInterface {
+	return &Price{DB: p.DB.WithContext(ctx)}
+}
+
+// recordPrice makes price the current price of its product within tx, ending
+// the period of the previous one when price takes effect.
+func recordPrice(tx *gorm.DB, price *entity.ProductPrice) error {
+	err := tx.Model(&entity.ProductPrice{}).
+		Where("product_id = ? AND applied_at IS NOT NULL AND effective_to IS NULL", price.ProductID).
+		Update("effective_to", price.EffectiveFrom).Error
+	if err != nil {
+		return err
+	}
+
+	if price.UserID == "" && tx.Statement.Context != nil {
+		price.UserID = AuditActorFrom(tx.Statement.Context).UserID
+	}
+	return tx.Create(price).Error
+}
+
+// Timeline lists every price of the product, past, current and scheduled, in
+// the order they take effect.
+func (p *Price) Timeline(productID string) ([]entity.ProductPrice, error) {
+	if err := p.DB.Select("id").First(&entity.Product{}, "id = ?", productID).Error; err != nil {
+		return nil, err
+	}
+
+	prices := []entity.ProductPrice{}
+	err := p.DB.Where("product_id = ?", productID).Order("effective_from").Order("created_at").Find(&prices).Error
+	return prices, err
+}
+
+// Schedule saves a future price of a product. Only one price can be scheduled
+// at a given time.
+func (p *Price) Schedule(price *entity.ProductPrice) error {
+	return p.DB.Transaction(func(tx *gorm.DB) error {
+		var product entity.Product
+		if err := tx.Select("id", "price_currency").First(&product, "id = ?", price.ProductID).Error; err != nil {
+			return err
+		}
+		if price.Price.Currency != product.Price.Currency {
+			product.Price = price.Price
+			if err := checkVariantCurrency(tx, &product); err != nil {
+				return err
+			}
+		}
+
+		var taken int64
+		err := tx.Model(&entity.ProductPrice{}).
+			Where("product_id = ? AND applied_at IS NULL AND effective_from = ?", price.ProductID, price.EffectiveFrom).
+			Count(&taken).Error
+		if err != nil {
+			return err
+		}
+		if taken > 0 {
+			return entity.ErrPriceAlreadyScheduled
+		}
+
+		return tx.Create(price).Error
+	})
+}
+
+// Cancel deletes a price of the product that is still scheduled.
+func (p *Price) Cancel(productID, id string) error {
+	var price entity.ProductPrice
+	if err := p.DB.First(&price, "id = ? AND product_id = ?", id, productID).Error; err != nil {
+		return err
+	}
+	if price.AppliedAt != nil {
+		return entity.ErrPriceNotScheduled
+	}
+
+	result := p.DB.Where("id = ? AND applied_at IS NULL", id).Delete(&entity.ProductPrice{})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return entity.ErrPriceNotScheduled
+	}
+	return result.Error
+}
+
+// ApplyDue makes the scheduled prices due at now the prices of their
+// products, in the order they were due, each in its own transaction. Applying
+// a price moves the product to its next version and is audited as an update.
+// Prices in a currency that variants added since they were scheduled do not
+// share stay scheduled, and prices cancelled meanwhile are left out. It returns
+// how many prices were applied.
+func (p *Price) ApplyDue(now time.Time) (int64, error) {
+	var due []entity.ProductPrice
+	err := p.DB.Where("applied_at IS NULL AND effective_from <= ?", now).
+		Order("effective_from").Order("created_at").Find(&due).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var applied int64
+	for i := range due {
+		var claimed bool
+		err = p.DB.Transaction(func(tx *gorm.DB) (err error) {
+			claimed, err = applyPrice(tx, &due[i], now)
+			return err
+		})
+		if errors.Is(err, entityPkg.ErrCurrencyMismatch) {
+			continue
+		}
+		if err != nil {
+			return applied, err
+		}
+		if claimed {
+			applied++
+		}
+	}
+	return applied, nil
+}
+
+// applyPrice makes price the price of its product. It tells whether price was
+// still scheduled: one cancelled meanwhile leaves everything as it was.
+func applyPrice(tx *gorm.DB, price *entity.ProductPrice, now time.Time) (bool, error) {
+	// trashed products get their price too, so that they come back with it
+	var product entity.Product
+	if err := tx.Unscoped().First(&product, "id = ?", price.ProductID).Error; err != nil {
+		return false, err
+	}
+	before := product.AuditFields()
+
+	// variants may have been given their own price since this one was
+	// scheduled
+	if price.Price.Currency != product.Price.Currency {
+		target := product
+		target.Price = price.Price
+		if err := checkVariantCurrency(tx, &target); err != nil {
+			return false, err
+		}
+	}
+
+	// when the price was changed by hand after this one was due, it only takes
+	// effect now, so that the timeline stays in order
+	var current entity.ProductPrice
+	err := tx.Where("product_id = ? AND applied_at IS NOT NULL AND effective_to IS NULL", price.ProductID).First(&current).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+	if err == nil && current.EffectiveFrom.After(price.EffectiveFrom) {
+		price.EffectiveFrom = now
+	}
+
+	// claim the price first: once cancelled, the current period must stay open
+	result := tx.Model(price).Where("applied_at IS NULL").
+		Updates(map[string]interface{}{"applied_at": now, "effective_from": price.EffectiveFrom})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+
+	err = tx.Model(&entity.ProductPrice{}).
+		Where("product_id = ? AND id <> ? AND applied_at IS NOT NULL AND effective_to IS NULL", price.ProductID, price.ID).
+		Update("effective_to", price.EffectiveFrom).Error
+	if err != nil {
+		return false, err
+	}
+
+	product.Price = price.Price
+	err = tx.Unscoped().Model(&product).Updates(map[string]interface{}{
+		"price_amount":   price.Price.Amount,
+		"price_currency": price.Price.Currency,
+		"version":        gorm.Expr("version + 1"),
+	}).Error
+	if err != nil {
+		return false, err
+	}
+
+	return true, productAudit(tx, product.ID, entity.AuditUpdate, before, product.AuditFields())
+}
@@ -0,0 +1,166 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	pkgEntity "github.com/sallescosta/user-and-products-manager/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestPriceTimeline(t *testing.T) {
+	db := NewTestDB(t)
+	productDB := NewProduct(db)
+	priceDB := NewPrice(db)
+
+	product, _ := entity.NewProduct(name, price)
+	assert.NoError(t, productDB.Create(product))
+
+	// renaming keeps the price period, repricing starts a new one
+	product.Name = "Renamed"
+	assert.NoError(t, productDB.Update(product))
+	product.Price = pkgEntity.Money{Amount: 1299, Currency: "USD"}
+	assert.NoError(t, productDB.Update(product))
+
+	userID := pkgEntity.NewID()
+	scheduled, err := entity.NewScheduledPrice(product.ID, userID, pkgEntity.Money{Amount: 999, Currency: "USD"}, time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.NoError(t, priceDB.Schedule(scheduled))
+
+	clash, _ := entity.NewScheduledPrice(product.ID, userID, pkgEntity.Money{Amount: 1, Currency: "USD"}, scheduled.EffectiveFrom)
+	assert.ErrorIs(t, priceDB.Schedule(clash), entity.ErrPriceAlreadyScheduled)
+
+	timeline, err := priceDB.Timeline(product.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, timeline, 3)
+	assert.Equal(t, []entity.PriceStatus{entity.PricePast, entity.PriceCurrent, entity.PriceScheduled},
+		[]entity.PriceStatus{timeline[0].Status, timeline[1].Status, timeline[2].Status})
+	assert.Equal(t, int64(1034), timeline[0].Price.Amount)
+	assert.Equal(t, timeline[1].EffectiveFrom, *timeline[0].EffectiveTo)
+
+	_, err = priceDB.Timeline(pkgEntity.NewID().String())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestApplyDuePrices(t *testing.T) {
+	db := NewTestDB(t)
+	productDB := NewProduct(db)
+	priceDB := NewPrice(db)
+
+	product, _ := entity.NewProduct(name, price)
+	assert.NoError(t, productDB.Create(product))
+
+	soon, _ := entity.NewScheduledPrice(product.ID, pkgEntity.NewID(), pkgEntity.Money{Amount: 999, Currency: "USD"}, time.Now().Add(time.Minute))
+	later, _ := entity.NewScheduledPrice(product.ID, pkgEntity.NewID(), pkgEntity.Money{Amount: 899, Currency: "USD"}, time.Now().Add(2*time.Minute))
+	cancelled, _ := entity.NewScheduledPrice(product.ID, pkgEntity.NewID(), pkgEntity.Money{Amount: 1, Currency: "USD"}, time.Now().Add(3*time.Minute))
+	for _, p := range []*entity.ProductPrice{soon, later, cancelled} {
+		assert.NoError(t, priceDB.Schedule(p))
+	}
+	assert.NoError(t, priceDB.Cancel(product.ID.String(), cancelled.ID.String()))
+
+	applied, err := priceDB.ApplyDue(time.Now())
+	assert.NoError(t, err)
+	assert.Zero(t, applied)
+
+	applied, err = priceDB.ApplyDue(time.Now().Add(5 * time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), applied)
+
+	found, err := productDB.FindById(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(899), found.Price.Amount)
+	assert.Equal(t, 3, found.Version)
+
+	timeline, err := priceDB.Timeline(product.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, timeline, 3)
+	assert.WithinDuration(t, soon.EffectiveFrom, *timeline[0].EffectiveTo, 0)
+	assert.WithinDuration(t, later.EffectiveFrom, *timeline[1].EffectiveTo, 0)
+	assert.Equal(t, entity.PriceCurrent, timeline[2].Status)
+
+	// applied prices cannot be cancelled anymore
+	assert.ErrorIs(t, priceDB.Cancel(product.ID.String(), later.ID.String()), entity.ErrPriceNotScheduled)
+
+	history, err := NewAudit(db).FindByQuery(AuditQuery{EntityID: product.ID.String(), Action: entity.AuditUpdate})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, history.Total)
+	assert.Empty(t, history.Entries[0].ActorID)
+}
+
+func TestScheduledPriceVariantCurrency(t *testing.T) {
+	db := NewTestDB(t)
+	productDB := NewProduct(db)
+	priceDB := NewPrice(db)
+
+	product, _ := entity.NewProduct(name, price)
+	assert.NoError(t, productDB.Create(product))
+	other, _ := entity.NewProduct("Other", price)
+	assert.NoError(t, productDB.Create(other))
+
+	euros, _ := entity.NewScheduledPrice(product.ID, pkgEntity.NewID(), pkgEntity.Money{Amount: 999, Currency: "EUR"}, time.Now().Add(time.Minute))
+	assert.NoError(t, priceDB.Schedule(euros))
+	dollars, _ := entity.NewScheduledPrice(other.ID, pkgEntity.NewID(), pkgEntity.Money{Amount: 999, Currency: "USD"}, time.Now().Add(2*time.Minute))
+	assert.NoError(t, priceDB.Schedule(dollars))
+
+	// a variant priced in dollars added after the euro price was scheduled
+	variant, _ := entity.NewVariant(product.ID, "SKU-1", nil, &pkgEntity.Money{Amount: 1500, Currency: "USD"}, 0)
+	assert.NoError(t, NewVariant(db).Create(variant))
+
+	later, _ := entity.NewScheduledPrice(product.ID, pkgEntity.NewID(), pkgEntity.Money{Amount: 899, Currency: "EUR"}, time.Now().Add(time.Hour))
+	assert.ErrorIs(t, priceDB.Schedule(later), pkgEntity.ErrCurrencyMismatch)
+
+	// the euro price stays scheduled, without holding back the others
+	applied, err := priceDB.ApplyDue(time.Now().Add(5 * time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), applied)
+
+	found, err := productDB.FindById(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, "USD", found.Price.Currency)
+	assert.Equal(t, product.Version+1, found.Version)
+
+	timeline, err := priceDB.Timeline(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, entity.PriceScheduled, timeline[len(timeline)-1].Status)
+}
+
+func TestApplyDuePrices_CancelledMeanwhile(t *testing.T) {
+	db := NewTestDB(t)
+	productDB := NewProduct(db)
+	priceDB := NewPrice(db)
+
+	product, _ := entity.NewProduct(name, price)
+	assert.NoError(t, productDB.Create(product))
+	scheduled, _ := entity.NewScheduledPrice(product.ID, pkgEntity.NewID(), pkgEntity.Money{Amount: 999, Currency: "USD"}, time.Now().Add(time.Minute))
+	assert.NoError(t, priceDB.Schedule(scheduled))
+
+	// cancel the price right after ApplyDue found it due
+	cancelled := false
+	assert.NoError(t, db.Callback().Query().After("gorm:query").Register("test:cancel_price", func(tx *gorm.DB) {
+		if cancelled || tx.Statement.Table != "product_prices" {
+			return
+		}
+		cancelled = true
+		assert.NoError(t, priceDB.Cancel(product.ID.String(), scheduled.ID.String()))
+	}))
+
+	applied, err := priceDB.ApplyDue(time.Now().Add(5 * time.Minute))
+	assert.NoError(t, err)
+	assert.True(t, cancelled)
+	assert.Zero(t, applied)
+	assert.NoError(t, db.Callback().Query().Remove("test:cancel_price"))
+
+	found, err := productDB.FindById(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, price, found.Price)
+	assert.Equal(t, product.Version, found.Version)
+
+	// the current period is still open
+	timeline, err := priceDB.Timeline(product.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, timeline, 1)
+	assert.Equal(t, entity.PriceCurrent, timeline[0].Status)
+	assert.Nil(t, timeline[0].EffectiveTo)
+}
@@ -1,7 +1,13 @@
 package database
 
 import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
 	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	entityPkg "github.com/sallescosta/user-and-products-manager/pkg/entity"
 	"gorm.io/gorm"
 )
 
@@ -13,54 +19,396 @@ func NewProduct(db *gorm.DB) *Product {
 	return &Product{DB: db}
 }
 
+// WithContext returns the repository bound to ctx.
+func (p *Product) WithContext(ctx context.Context) ProductInterface {
+	return &Product{DB: p.DB.WithContext(ctx)}
+}
+
+// productAudit records a change of the product with id.
+func productAudit(tx *gorm.DB, id entityPkg.ID, action entity.AuditAction, before, after map[string]interface{}) error {
+	entry := entity.NewAuditEntry("product", id, action, before, after)
+	entry.ProductID = &id
+	return recordAudit(tx, entry)
+}
+
 func (p *Product) Create(product *entity.Product) error {
-	return p.DB.Create(product).Error
+	return p.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(product).Error; err != nil {
+			return err
+		}
+		if err := recordPrice(tx, entity.NewProductPrice(product.ID, product.Price)); err != nil {
+			return err
+		}
+		return productAudit(tx, product.ID, entity.AuditCreate, nil, product.AuditFields())
+	})
+}
+
+// CreateBatch saves products in a single transaction: either all of them or,
+// on error, none. Unlike Create in a loop, it inserts them, their prices and
+// their audit entries a hundred rows per statement.
+func (p *Product) CreateBatch(products []*entity.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	var actor AuditActor
+	if ctx := p.DB.Statement.Context; ctx != nil {
+		actor = AuditActorFrom(ctx)
+	}
+
+	// new products have no price period to close, see recordPrice
+	prices := make([]*entity.ProductPrice, len(products))
+	entries := make([]*entity.AuditEntry, len(products))
+	for i, product := range products {
+		prices[i] = entity.NewProductPrice(product.ID, product.Price)
+		prices[i].UserID = actor.UserID
+		entries[i] = entity.NewAuditEntry("product", product.ID, entity.AuditCreate, nil, product.AuditFields())
+		entries[i].ProductID = &products[i].ID
+		entries[i].ActorID, entries[i].RequestID = actor.UserID, actor.RequestID
+	}
+
+	return p.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.CreateInBatches(products, 100).Error; err != nil {
+			return err
+		}
+		if err := tx.CreateInBatches(prices, 100).Error; err != nil {
+			return err
+		}
+		return tx.CreateInBatches(entries, 100).Error
+	})
 }
 
 func (p *Product) FindById(id string) (*entity.Product, error) {
 	var product entity.Product
-	err := p.DB.First(&product, "id = ?", id).Error
+	err := p.DB.Preload("Categories").Preload("Variants", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sku")
+	}).Preload("Images", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position")
+	}).First(&product, "id = ?", id).Error
 	return &product, err
 }
 
+// Update saves product, provided it is still at the version it was read at,
+// and moves it to the next version. When someone else updated it meanwhile,
+// it returns entity.ErrVersionMismatch and saves nothing.
 func (p *Product) Update(product *entity.Product) error {
-	_, err := p.FindById(product.ID.String())
+	expected := product.Version
+	product.Version++
+
+	err := p.DB.Transaction(func(tx *gorm.DB) error {
+		var before entity.Product
+		if err := tx.Where("version = ?", expected).First(&before, "id = ?", product.ID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return versionMismatch(tx, product.ID.String())
+			}
+			return err
+		}
+
+		if product.Price.Currency != before.Price.Currency {
+			if err := checkVariantCurrency(tx, product); err != nil {
+				return err
+			}
+		}
+
+		// the quantity only changes through stock movements
+		result := tx.Model(product).Where("version = ?", expected).
+			Select("*").Omit("ID", "CreatedAt", "DeletedAt", "Categories", "Variants", "Images", "Quantity").
+			Updates(product)
+		if result.Error == nil && result.RowsAffected == 0 {
+			result.Error = versionMismatch(tx, product.ID.String())
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if product.Price != before.Price {
+			if err := recordPrice(tx, entity.NewProductPrice(product.ID, product.Price)); err != nil {
+				return err
+			}
+		}
+		return productAudit(tx, product.ID, entity.AuditUpdate, before.AuditFields(), product.AuditFields())
+	})
+	if err != nil {
+		product.Version = expected
+	}
+	return err
+}
+
+// checkVariantCurrency refuses to change the currency of product while some
+// of its variants have a price override in the former one.
+func checkVariantCurrency(tx *gorm.DB, product *entity.Product) error {
+	var overrides int64
+	err := tx.Model(&entity.Variant{}).
+		Where("product_id = ? AND price_currency IS NOT NULL AND price_currency <> ?", product.ID, product.Price.Currency).
+		Count(&overrides).Error
 	if err != nil {
 		return err
 	}
-	return p.DB.Save(product).Error
+	if overrides > 0 {
+		return entityPkg.ErrCurrencyMismatch
+	}
+	return nil
 }
 
-func (p *Product) Delete(id string) error {
+// versionMismatch tells why a conditional write on the product with id
+// changed nothing: either it is gone, or it is at another version.
+func versionMismatch(tx *gorm.DB, id string) error {
+	if err := tx.Select("id").First(&entity.Product{}, "id = ?", id).Error; err != nil {
+		return err
+	}
+	return entity.ErrVersionMismatch
+}
+
+// touchProduct moves the product with id to its next version within tx. It
+// follows changes to what is returned along with the product, such as its
+// variants and images, so that its ETag changes with them.
+func touchProduct(tx *gorm.DB, id string) error {
+	return tx.Unscoped().Model(&entity.Product{}).Where("id = ?", id).
+		Update("version", gorm.Expr("version + 1")).Error
+}
+
+// Delete moves the product to the trash, provided it is still at version. It
+// keeps its categories, so that it comes back as it was when restored.
+func (p *Product) Delete(id string, version int) error {
+	return p.DB.Transaction(func(tx *gorm.DB) error {
+		var product entity.Product
+		if err := tx.Where("version = ?", version).First(&product, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return versionMismatch(tx, id)
+			}
+			return err
+		}
+
+		result := tx.Where("id = ? AND version = ?", id, version).Delete(&entity.Product{})
+		if result.Error == nil && result.RowsAffected == 0 {
+			result.Error = versionMismatch(tx, id)
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+
+		return productAudit(tx, product.ID, entity.AuditDelete, product.AuditFields(), nil)
+	})
+}
+
+// FindDeleted lists the products in the trash, the most recently deleted
+// first.
+func (p *Product) FindDeleted(page, limit int) (ProductResponse, error) {
+	count := p.DB.Unscoped().Model(&entity.Product{}).Where("deleted_at IS NOT NULL")
+	find := p.DB.Unscoped().Preload("Categories").Where("deleted_at IS NOT NULL").Order("deleted_at desc").Order("id")
+	return paginateProducts(count, find, page, limit)
+}
+
+// Restore takes the product back out of the trash.
+func (p *Product) Restore(id string) error {
+	return p.DB.Transaction(func(tx *gorm.DB) error {
+		var product entity.Product
+		if err := tx.Unscoped().Where("deleted_at IS NOT NULL").First(&product, "id = ?", id).Error; err != nil {
+			return err
+		}
+
+		result := tx.Unscoped().Model(&entity.Product{}).
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			Update("deleted_at", nil)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return productAudit(tx, product.ID, entity.AuditRestore, nil, nil)
+	})
+}
+
+// Purge permanently deletes the products trashed before the given time, with
+// their category assignments, prices and variants. It returns how many were
+// deleted. Their audit entries are kept, and their images are left to
+// Image.PurgeOrphans, which also deletes their files.
+func (p *Product) Purge(before time.Time) (int64, error) {
+	var purged int64
+	err := p.DB.Transaction(func(tx *gorm.DB) error {
+		var expired []entity.Product
+		if err := tx.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", before).Find(&expired).Error; err != nil {
+			return err
+		}
+		if len(expired) == 0 {
+			return nil
+		}
+
+		ids := tx.Unscoped().Model(&entity.Product{}).Select("id").Where("deleted_at IS NOT NULL AND deleted_at < ?", before)
+		for _, table := range []string{"product_categories", "product_prices", "variants", "stock_movements"} {
+			if err := tx.Exec("DELETE FROM "+table+" WHERE product_id IN (?)", ids).Error; err != nil {
+				return err
+			}
+		}
+
+		result := tx.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", before).Delete(&entity.Product{})
+		if result.Error != nil {
+			return result.Error
+		}
+		purged = result.RowsAffected
+
+		for _, product := range expired {
+			if err := productAudit(tx, product.ID, entity.AuditPurge, product.AuditFields(), nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return purged, err
+}
+
+// SetCategories replaces the categories the product is assigned to.
+func (p *Product) SetCategories(id string, categoryIDs []string) error {
 	product, err := p.FindById(id)
 	if err != nil {
 		return err
 	}
-	return p.DB.Delete(product).Error
+
+	categories := []entity.Category{}
+	if len(categoryIDs) > 0 {
+		if err = p.DB.Where("id IN ?", categoryIDs).Find(&categories).Error; err != nil {
+			return err
+		}
+	}
+	if len(categories) != len(categoryIDs) {
+		return entity.ErrCategoryNotFound
+	}
+
+	return p.DB.Transaction(func(tx *gorm.DB) error {
+		before := categoryIDList(product.Categories)
+		if err := tx.Model(product).Association("Categories").Replace(categories); err != nil {
+			return err
+		}
+		if err := touchProduct(tx, product.ID.String()); err != nil {
+			return err
+		}
+		return productAudit(tx, product.ID, entity.AuditUpdate,
+			map[string]interface{}{"categories": before},
+			map[string]interface{}{"categories": categoryIDList(categories)})
+	})
+}
+
+// categoryIDList returns the sorted IDs of categories, as audited.
+func categoryIDList(categories []entity.Category) []string {
+	ids := make([]string, len(categories))
+	for i, category := range categories {
+		ids[i] = category.ID.String()
+	}
+	sort.Strings(ids)
+	return ids
 }
 
+// ProductResponse is a page of products. Next and Prev are links to the
+// neighbouring pages and NextCursor the position after the last product, all
+// filled in by the web layer. Page is left out when paging by cursor.
 type ProductResponse struct {
-	Total    int              `json:"total"`
-	Products []entity.Product `json:"products"`
+	Total      int              `json:"total"`
+	TotalPages int              `json:"total_pages"`
+	Page       int              `json:"page,omitempty"`
+	Limit      int              `json:"limit"`
+	Next       string           `json:"next,omitempty"`
+	Prev       string           `json:"prev,omitempty"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+	HasMore    bool             `json:"-"`
+	Products   []entity.Product `json:"products"`
+}
+
+// FindAll lists products. When categoryIDs are given, only products assigned
+// to at least one of them are returned.
+func (p *Product) FindAll(page, limit int, sort string, categoryIDs ...string) (ProductResponse, error) {
+	return p.FindByQuery(ProductQuery{
+		Page:        page,
+		Limit:       limit,
+		Sort:        []SortKey{{Field: "created_at", Desc: sort == "desc"}},
+		CategoryIDs: categoryIDs,
+	})
 }
 
-func (p *Product) FindAll(page, limit int, sort string) (ProductResponse, error) {
-	var response ProductResponse
-	var products []entity.Product
+// FindByQuery lists the products matching the filters of q, in its order.
+// With q.After set, it lists the products after that cursor instead of
+// paging by offset.
+func (p *Product) FindByQuery(q ProductQuery) (ProductResponse, error) {
+	count := q.filter(p.DB.Model(&entity.Product{}))
+	find := q.order(q.filter(p.DB.Preload("Categories")))
+	if q.After == nil {
+		return paginateProducts(count, find, q.Page, q.Limit)
+	}
 
-	var err error
-	if sort != "" && sort != "asc" && sort != "desc" {
-		sort = "asc"
+	find = q.After.apply(find)
+	response, err := paginateProducts(count, find.Limit(q.Limit+1), 0, 0)
+	response.Page, response.Limit = 0, q.Limit
+	if q.Limit > 0 {
+		response.TotalPages = (response.Total + q.Limit - 1) / q.Limit
+	}
+	if len(response.Products) > q.Limit {
+		response.HasMore = true
+		response.Products = response.Products[:q.Limit]
 	}
+	return response, err
+}
 
-	if page != 0 && limit != 0 {
-		err = p.DB.Limit(limit).Offset((page - 1) * limit).Order("created_at " + sort).Find(&products).Error
-	} else {
-		err = p.DB.Order("created_at " + sort).Find(&products).Error
+// Export calls fn with each product selected by q, in its order, reading them
+// from the database one at a time rather than all at once. Pagination fields
+// of q are ignored. An error from fn stops the export and is returned.
+func (p *Product) Export(q ProductQuery, fn func(product *entity.Product) error) error {
+	rows, err := q.order(q.filter(p.DB.Model(&entity.Product{}))).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var product entity.Product
+		if err = p.DB.ScanRows(rows, &product); err != nil {
+			return err
+		}
+		if err = fn(&product); err != nil {
+			return err
+		}
 	}
+	return rows.Err()
+}
 
-	response.Products = products
-	response.Total = len(products)
+// pageInfo places a page among the rows of a query.
+type pageInfo struct {
+	Total      int
+	TotalPages int
+	Page       int
+	HasMore    bool
+}
+
+// paginate counts the rows of count and finds one page of find, which must
+// select the same rows, into dest. A zero limit finds them all; a zero page is
+// the first one.
+func paginate(count, find *gorm.DB, page, limit int, dest interface{}) (pageInfo, error) {
+	info := pageInfo{Page: 1}
+
+	var total int64
+	if err := count.Count(&total).Error; err != nil {
+		return info, err
+	}
+	info.Total = int(total)
+
+	if limit > 0 {
+		if page > 0 {
+			info.Page = page
+		}
+		info.TotalPages = (info.Total + limit - 1) / limit
+		info.HasMore = info.Page*limit < info.Total
+		find = find.Limit(limit).Offset((info.Page - 1) * limit)
+	} else if total > 0 {
+		info.TotalPages = 1
+	}
+
+	return info, find.Find(dest).Error
+}
 
+// paginateProducts is paginate for the product listings.
+func paginateProducts(count, find *gorm.DB, page, limit int) (ProductResponse, error) {
+	response := ProductResponse{Limit: limit, Products: []entity.Product{}}
+	info, err := paginate(count, find, page, limit, &response.Products)
+	response.Total, response.TotalPages, response.Page, response.HasMore = info.Total, info.TotalPages, info.Page, info.HasMore
 	return response, err
 }
@@ -1,19 +1,22 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"testing"
+	"time"
 
+	"github.com/glebarez/sqlite"
 	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	pkgEntity "github.com/sallescosta/user-and-products-manager/pkg/entity"
 	"github.com/stretchr/testify/assert"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
 var (
 	name    = "Product 1"
-	price   = 10.34
+	price   = pkgEntity.Money{Amount: 1034, Currency: "USD"}
 	perPage = 10
 )
 
@@ -23,7 +26,7 @@ func NewTestDB(t *testing.T) *gorm.DB {
 		t.Error(err)
 	}
 
-	if err := db.AutoMigrate(&entity.Product{}); err != nil {
+	if err := db.AutoMigrate(&entity.Product{}, &entity.Category{}, &entity.StockMovement{}, &entity.AuditEntry{}, &entity.ProductPrice{}, &entity.Variant{}, &entity.ProductImage{}); err != nil {
 		t.Error(err)
 	}
 
@@ -38,7 +41,7 @@ func TestDeleteProduct(t *testing.T) {
 	db.Create(product)
 	productDB := NewProduct(db)
 
-	err = productDB.Delete(product.ID.String())
+	err = productDB.Delete(product.ID.String(), product.Version)
 	assert.NoError(t, err)
 
 	_, err = productDB.FindById(product.ID.String())
@@ -58,10 +61,35 @@ func TestCreateNewProduct(t *testing.T) {
 	assert.NotEmpty(t, product.ID)
 }
 
+func TestCreateProductBatch(t *testing.T) {
+	db := NewTestDB(t)
+	productDB := NewProduct(db)
+
+	first, _ := entity.NewProduct("Desk", price)
+	second, _ := entity.NewProduct("Chair", price)
+	ctx := WithAuditActor(context.Background(), AuditActor{UserID: "importer", RequestID: "req-1"})
+	assert.NoError(t, productDB.WithContext(ctx).CreateBatch([]*entity.Product{first, second}))
+
+	var prices int64
+	db.Model(&entity.ProductPrice{}).Where("product_id IN ? AND user_id = ?", []string{first.ID.String(), second.ID.String()}, "importer").Count(&prices)
+	assert.Equal(t, int64(2), prices)
+
+	history, err := NewAudit(db).FindByQuery(AuditQuery{ActorID: "importer", Action: entity.AuditCreate})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, history.Total)
+	assert.Equal(t, "req-1", history.Entries[0].RequestID)
+
+	// a failing product rolls the whole batch back
+	third, _ := entity.NewProduct("Lamp", price)
+	assert.Error(t, productDB.CreateBatch([]*entity.Product{third, first}))
+	_, err = productDB.FindById(third.ID.String())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
 func TestFindAllProducts(t *testing.T) {
 	db := NewTestDB(t)
 
-	var precoSorteado = rand.Float64() * 100
+	var precoSorteado = pkgEntity.Money{Amount: rand.Int63n(10000) + 1, Currency: "USD"}
 
 	for i := 1; i < 33; i++ {
 		product, err := entity.NewProduct(fmt.Sprintf("Produto %d", i), precoSorteado)
@@ -76,6 +104,10 @@ func TestFindAllProducts(t *testing.T) {
 	assert.Len(t, response.Products, perPage)
 	assert.Equal(t, "Produto 1", response.Products[0].Name)
 	assert.Equal(t, "Produto 10", response.Products[9].Name)
+	assert.Equal(t, 32, response.Total)
+	assert.Equal(t, 4, response.TotalPages)
+	assert.Equal(t, 1, response.Page)
+	assert.Equal(t, perPage, response.Limit)
 
 	response, err = productDB.FindAll(2, perPage, "asc")
 	assert.NoError(t, err)
@@ -95,6 +127,18 @@ func TestFindAllProducts(t *testing.T) {
 	assert.Len(t, response.Products, 2)
 	assert.Equal(t, "Produto 31", response.Products[0].Name)
 	assert.Equal(t, "Produto 32", response.Products[1].Name)
+	assert.Equal(t, 32, response.Total)
+	assert.Equal(t, 4, response.Page)
+
+	response, err = productDB.FindAll(5, perPage, "asc")
+	assert.NoError(t, err)
+	assert.Empty(t, response.Products)
+	assert.Equal(t, 32, response.Total)
+
+	response, err = productDB.FindAll(0, 0, "asc")
+	assert.NoError(t, err)
+	assert.Len(t, response.Products, 32)
+	assert.Equal(t, 1, response.TotalPages)
 }
 
 func TestFindProductByID(t *testing.T) {
@@ -130,3 +174,101 @@ func TestUpdateProduct(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "Produto 2", product.Name)
 }
+
+func TestDeleteAndRestoreProduct(t *testing.T) {
+	db := NewTestDB(t)
+	productDB := NewProduct(db)
+	categoryDB := NewCategory(db)
+
+	category, _ := entity.NewCategory("Books", nil)
+	assert.NoError(t, categoryDB.Create(category))
+	product, _ := entity.NewProduct(name, price)
+	assert.NoError(t, productDB.Create(product))
+	assert.NoError(t, productDB.SetCategories(product.ID.String(), []string{category.ID.String()}))
+
+	assert.NoError(t, productDB.Delete(product.ID.String(), product.Version+1))
+
+	response, err := productDB.FindAll(0, 0, "asc")
+	assert.NoError(t, err)
+	assert.Empty(t, response.Products)
+
+	trash, err := productDB.FindDeleted(1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, trash.Total)
+	assert.Equal(t, product.ID, trash.Products[0].ID)
+	assert.True(t, trash.Products[0].DeletedAt.Valid)
+
+	assert.NoError(t, productDB.Restore(product.ID.String()))
+	assert.Equal(t, gorm.ErrRecordNotFound, productDB.Restore(product.ID.String()))
+
+	restored, err := productDB.FindById(product.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, restored.Categories, 1)
+
+	trash, err = productDB.FindDeleted(1, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, trash.Products)
+}
+
+func TestPurgeProducts(t *testing.T) {
+	db := NewTestDB(t)
+	productDB := NewProduct(db)
+
+	old, _ := entity.NewProduct("Old", price)
+	recent, _ := entity.NewProduct("Recent", price)
+	kept, _ := entity.NewProduct("Kept", price)
+	for _, p := range []*entity.Product{old, recent, kept} {
+		assert.NoError(t, productDB.Create(p))
+
+		receipt, _ := entity.NewStockMovement(p.ID, pkgEntity.NewID(), entity.MovementReceipt, 5, "")
+		assert.NoError(t, NewStock(db).Record(receipt, false))
+		p.Version++
+	}
+	assert.NoError(t, productDB.Delete(old.ID.String(), old.Version))
+	assert.NoError(t, productDB.Delete(recent.ID.String(), recent.Version))
+	db.Unscoped().Model(old).Update("deleted_at", time.Now().Add(-48*time.Hour))
+
+	purged, err := productDB.Purge(time.Now().Add(-24 * time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	var count int64
+	db.Unscoped().Model(&entity.Product{}).Count(&count)
+	assert.Equal(t, int64(2), count)
+
+	// the stock ledger goes with the product
+	var movements []string
+	db.Model(&entity.StockMovement{}).Distinct().Pluck("product_id", &movements)
+	assert.ElementsMatch(t, []string{recent.ID.String(), kept.ID.String()}, movements)
+
+	assert.Equal(t, gorm.ErrRecordNotFound, productDB.Restore(old.ID.String()))
+	assert.NoError(t, productDB.Restore(recent.ID.String()))
+}
+
+func TestUpdateProduct_VersionMismatch(t *testing.T) {
+	db := NewTestDB(t)
+	productDB := NewProduct(db)
+
+	product, _ := entity.NewProduct(name, price)
+	assert.NoError(t, productDB.Create(product))
+
+	first, _ := productDB.FindById(product.ID.String())
+	second, _ := productDB.FindById(product.ID.String())
+
+	first.Name = "First"
+	assert.NoError(t, productDB.Update(first))
+	assert.Equal(t, 2, first.Version)
+
+	second.Name = "Second"
+	assert.Equal(t, entity.ErrVersionMismatch, productDB.Update(second))
+	assert.Equal(t, 1, second.Version)
+
+	stored, _ := productDB.FindById(product.ID.String())
+	assert.Equal(t, "First", stored.Name)
+	assert.Equal(t, 2, stored.Version)
+
+	assert.Equal(t, entity.ErrVersionMismatch, productDB.Delete(product.ID.String(), 1))
+	assert.NoError(t, productDB.Delete(product.ID.String(), 2))
+	assert.Equal(t, gorm.ErrRecordNotFound, productDB.Delete(product.ID.String(), 2))
+	assert.Equal(t, gorm.ErrRecordNotFound, productDB.Update(stored))
+}
@@ -0,0 +1,281 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	entityPkg "github.com/sallescosta/user-and-products-manager/pkg/entity"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnknownQueryParameter = errors.New("unknown query parameter")
+	ErrUnknownSortField      = errors.New("unknown sort field")
+	ErrInvalidFilter         = errors.New("invalid filter")
+	ErrInvalidCursor         = errors.New("invalid cursor")
+)
+
+// productSortFields whitelists the fields products can be sorted by, with the
+// columns each one sorts on.
+var productSortFields = map[string][]string{
+	"name":       {"name"},
+	"price":      {"price_currency", "price_amount"},
+	"quantity":   {"quantity"},
+	"created_at": {"created_at"},
+}
+
+// productQueryParameters are the query string parameters ParseProductQuery
+// understands. Anything else is rejected rather than silently ignored.
+var productQueryParameters = map[string]bool{
+	"page": true, "limit": true, "sort": true, "category": true, "name": true,
+	"price_min": true, "price_max": true, "currency": true,
+	"created_from": true, "created_to": true, "cursor": true,
+}
+
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// ProductQuery selects, orders and pages products. Zero values mean no
+// filter; prices are in minor units of Currency.
+type ProductQuery struct {
+	Page  int
+	Limit int
+	Sort  []SortKey
+
+	NameContains string
+	Currency     string
+	MinPrice     *int64
+	MaxPrice     *int64
+	CreatedFrom  *time.Time
+	CreatedTo    *time.Time
+
+	// CategoryID is the category asked for; CategoryIDs are the ones actually
+	// filtered on, normally CategoryID and its descendants.
+	CategoryID  string
+	CategoryIDs []string
+
+	// After switches to keyset pagination: only the products after it are
+	// listed, and Page is ignored.
+	After *Cursor
+}
+
+// Cursor is a position in a listing ordered by (created_at, id), the last
+// product of the previous page. It stays valid when products are inserted or
+// removed, unlike an offset.
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+	Desc      bool      `json:"d,omitempty"`
+}
+
+// NewCursor returns the cursor right after product in a listing by
+// created_at, descending or not.
+func NewCursor(product entity.Product, desc bool) Cursor {
+	return Cursor{CreatedAt: product.CreatedAt, ID: product.ID.String(), Desc: desc}
+}
+
+// Keyset reports whether q is ordered so that it can be paged by cursor, that
+// is by created_at alone.
+func (q ProductQuery) Keyset() bool {
+	return len(q.Sort) == 0 || (len(q.Sort) == 1 && q.Sort[0].Field == "created_at")
+}
+
+// Descending reports whether q lists the newest products first.
+func (q ProductQuery) Descending() bool {
+	return len(q.Sort) > 0 && q.Sort[len(q.Sort)-1].Desc
+}
+
+func (c Cursor) apply(query *gorm.DB) *gorm.DB {
+	op := ">"
+	if c.Desc {
+		op = "<"
+	}
+	return query.Where("(created_at "+op+" ? OR (created_at = ? AND id "+op+" ?))", c.CreatedAt, c.CreatedAt, c.ID)
+}
+
+// ParseProductQuery reads a ProductQuery from the query string of a product
+// listing:
+//
+//	?name=key&price_min=10&price_max=99.90&currency=USD
+//	&created_from=2024-01-01&created_to=2024-12-31T23:59:59Z
+//	&category={id}&sort=-price,name&page=1&limit=10
+//
+// Sort keys are separated by commas and descending when prefixed with "-".
+// The former sort=asc|desc still orders by created_at.
+func ParseProductQuery(values url.Values) (ProductQuery, error) {
+	var q ProductQuery
+
+	for key := range values {
+		if !productQueryParameters[key] {
+			return q, fmt.Errorf("%w: %s", ErrUnknownQueryParameter, key)
+		}
+	}
+
+	q.Page = nonNegative(values.Get("page"))
+	q.Limit = nonNegative(values.Get("limit"))
+
+	sort, err := parseSort(values.Get("sort"))
+	if err != nil {
+		return q, err
+	}
+	q.Sort = sort
+
+	q.NameContains = strings.TrimSpace(values.Get("name"))
+
+	if category := values.Get("category"); category != "" {
+		if _, err = entityPkg.ParseID(category); err != nil {
+			return q, entity.ErrInvalidId
+		}
+		q.CategoryID = category
+	}
+
+	q.Currency = strings.ToUpper(values.Get("currency"))
+	if q.Currency != "" {
+		if _, err = entityPkg.MinorUnits(q.Currency); err != nil {
+			return q, err
+		}
+	}
+	if q.MinPrice, err = parsePriceFilter(values, "price_min", &q.Currency); err != nil {
+		return q, err
+	}
+	if q.MaxPrice, err = parsePriceFilter(values, "price_max", &q.Currency); err != nil {
+		return q, err
+	}
+
+	if q.CreatedFrom, err = parseTimeFilter(values, "created_from", false); err != nil {
+		return q, err
+	}
+	if q.CreatedTo, err = parseTimeFilter(values, "created_to", true); err != nil {
+		return q, err
+	}
+
+	return q, nil
+}
+
+func nonNegative(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+func parseSort(s string) ([]SortKey, error) {
+	switch s {
+	case "":
+		return nil, nil
+	case "asc", "desc":
+		return []SortKey{{Field: "created_at", Desc: s == "desc"}}, nil
+	}
+
+	var keys []SortKey
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		key := SortKey{Field: strings.TrimPrefix(field, "-"), Desc: strings.HasPrefix(field, "-")}
+		if _, ok := productSortFields[key.Field]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownSortField, key.Field)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// parsePriceFilter reads a decimal price bound. Bounds given without a
+// currency are in the default one.
+func parsePriceFilter(values url.Values, key string, currency *string) (*int64, error) {
+	s := values.Get(key)
+	if s == "" {
+		return nil, nil
+	}
+	if *currency == "" {
+		*currency = entityPkg.DefaultCurrency
+	}
+
+	price, err := entityPkg.ParseMoney(s, *currency)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidFilter, key, err)
+	}
+	return &price.Amount, nil
+}
+
+// parseTimeFilter accepts RFC 3339 timestamps or plain dates. A plain date as
+// upper bound covers the whole day.
+func parseTimeFilter(values url.Values, key string, upper bool) (*time.Time, error) {
+	s := values.Get(key)
+	if s == "" {
+		return nil, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be a date or an RFC 3339 timestamp", ErrInvalidFilter, key)
+	}
+	if upper {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return &t, nil
+}
+
+// filter adds the filters of q to query.
+func (q ProductQuery) filter(query *gorm.DB) *gorm.DB {
+	if q.NameContains != "" {
+		query = query.Where("LOWER(name) LIKE ? ESCAPE '!'", "%"+escapeLike(strings.ToLower(q.NameContains))+"%")
+	}
+	if q.Currency != "" {
+		query = query.Where("price_currency = ?", q.Currency)
+	}
+	if q.MinPrice != nil {
+		query = query.Where("price_amount >= ?", *q.MinPrice)
+	}
+	if q.MaxPrice != nil {
+		query = query.Where("price_amount <= ?", *q.MaxPrice)
+	}
+	if q.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", *q.CreatedFrom)
+	}
+	if q.CreatedTo != nil {
+		query = query.Where("created_at <= ?", *q.CreatedTo)
+	}
+	if len(q.CategoryIDs) > 0 {
+		query = query.Where("id IN (?)", query.Session(&gorm.Session{NewDB: true}).Table("product_categories").Select("product_id").Where("category_id IN ?", q.CategoryIDs))
+	}
+	return query
+}
+
+// order adds the sort keys of q to query.
+func (q ProductQuery) order(query *gorm.DB) *gorm.DB {
+	sort := q.Sort
+	if len(sort) == 0 {
+		sort = []SortKey{{Field: "created_at"}}
+	}
+	for _, key := range sort {
+		for _, column := range productSortFields[key.Field] {
+			if key.Desc {
+				column += " desc"
+			}
+			query = query.Order(column)
+		}
+	}
+	// the ID breaks ties, so that pages never overlap, in the direction of the
+	// last key so that (created_at, id) keeps a single direction for cursors
+	if q.Descending() {
+		return query.Order("id desc")
+	}
+	return query.Order("id")
+}
+
+// escapeLike escapes the LIKE wildcards of s, with "!" as the escape character
+// since the backslash means different things to each backend.
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
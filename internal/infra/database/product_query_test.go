@@ -0,0 +1,207 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	pkgEntity "github.com/sallescosta/user-and-products-manager/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProductQuery(t *testing.T) {
+	values, _ := url.ParseQuery("name=Key&price_min=10&price_max=99.9&created_from=2024-01-01&created_to=2024-01-31&sort=-price,name&page=2&limit=5")
+
+	q, err := ParseProductQuery(values)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, q.Page)
+	assert.Equal(t, 5, q.Limit)
+	assert.Equal(t, "Key", q.NameContains)
+	assert.Equal(t, "USD", q.Currency)
+	assert.Equal(t, int64(1000), *q.MinPrice)
+	assert.Equal(t, int64(9990), *q.MaxPrice)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), *q.CreatedFrom)
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC).Add(-time.Nanosecond), *q.CreatedTo)
+	assert.Equal(t, []SortKey{{Field: "price", Desc: true}, {Field: "name"}}, q.Sort)
+}
+
+func TestParseProductQuery_LegacySort(t *testing.T) {
+	q, err := ParseProductQuery(url.Values{"sort": {"desc"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []SortKey{{Field: "created_at", Desc: true}}, q.Sort)
+}
+
+func TestParseProductQuery_Invalid(t *testing.T) {
+	cases := map[string]error{
+		"color=red":                ErrUnknownQueryParameter,
+		"sort=password":            ErrUnknownSortField,
+		"sort=name,-secret":        ErrUnknownSortField,
+		"price_min=abc":            ErrInvalidFilter,
+		"price_min=1.001":          ErrInvalidFilter,
+		"currency=XYZ":             pkgEntity.ErrInvalidCurrency,
+		"created_from=yesterday":   ErrInvalidFilter,
+		"category=not-a-uuid":      entity.ErrInvalidId,
+		"created_to=2024-13-01":    ErrInvalidFilter,
+		"name=a&unknown=1&page=1":  ErrUnknownQueryParameter,
+		"sort=created_at,-unknown": ErrUnknownSortField,
+	}
+
+	for raw, want := range cases {
+		values, _ := url.ParseQuery(raw)
+		_, err := ParseProductQuery(values)
+		assert.ErrorIs(t, err, want, raw)
+	}
+}
+
+func TestFindProductsByQuery(t *testing.T) {
+	db := NewTestDB(t)
+	productDB := NewProduct(db)
+
+	start := time.Now().Add(-time.Hour)
+	products := []struct {
+		name   string
+		amount int64
+	}{
+		{"Mechanical keyboard", 12000},
+		{"Keyboard 100%", 4000},
+		{"Mouse", 2500},
+		{"Monitor", 90000},
+	}
+	for i, p := range products {
+		product, err := entity.NewProduct(p.name, pkgEntity.Money{Amount: p.amount, Currency: "USD"})
+		assert.NoError(t, err)
+		product.CreatedAt = start.Add(time.Duration(i) * time.Minute)
+		assert.NoError(t, productDB.Create(product))
+	}
+	yen, _ := entity.NewProduct("Keyboard", pkgEntity.Money{Amount: 5000, Currency: "JPY"})
+	assert.NoError(t, productDB.Create(yen))
+
+	names := func(q ProductQuery) []string {
+		response, err := productDB.FindByQuery(q)
+		assert.NoError(t, err)
+		var names []string
+		for _, p := range response.Products {
+			names = append(names, p.Name)
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"Mechanical keyboard", "Keyboard 100%", "Keyboard"}, names(ProductQuery{NameContains: "KEYBOARD"}))
+	assert.Equal(t, []string{"Keyboard 100%"}, names(ProductQuery{NameContains: "100%"}))
+
+	min, max := int64(3000), int64(20000)
+	assert.Equal(t, []string{"Keyboard 100%", "Mechanical keyboard"}, names(ProductQuery{
+		Currency: "USD",
+		MinPrice: &min,
+		MaxPrice: &max,
+		Sort:     []SortKey{{Field: "price"}},
+	}))
+
+	from, to := start.Add(time.Minute), start.Add(2*time.Minute)
+	assert.Equal(t, []string{"Mouse", "Keyboard 100%"}, names(ProductQuery{
+		CreatedFrom: &from,
+		CreatedTo:   &to,
+		Sort:        []SortKey{{Field: "created_at", Desc: true}},
+	}))
+
+	assert.Equal(t, []string{"Keyboard", "Keyboard 100%", "Mechanical keyboard"}, names(ProductQuery{
+		NameContains: "keyboard",
+		Sort:         []SortKey{{Field: "name"}},
+	}))
+
+	assert.Equal(t, []string{"Monitor", "Mouse"}, names(ProductQuery{
+		NameContains: "mo",
+		Sort:         []SortKey{{Field: "price", Desc: true}, {Field: "name"}},
+		Page:         1,
+		Limit:        2,
+	}))
+}
+
+func TestFindProductsByCursor(t *testing.T) {
+	db := NewTestDB(t)
+	productDB := NewProduct(db)
+
+	// products created in the same instant are told apart by their ID
+	start := time.Now().Truncate(time.Second)
+	for i := 0; i < 7; i++ {
+		product, _ := entity.NewProduct(fmt.Sprintf("Product %d", i), price)
+		product.CreatedAt = start.Add(time.Duration(i/2) * time.Second)
+		assert.NoError(t, productDB.Create(product))
+	}
+
+	for _, desc := range []bool{false, true} {
+		q := ProductQuery{Limit: 3, Sort: []SortKey{{Field: "created_at", Desc: desc}}}
+		all, err := productDB.FindByQuery(ProductQuery{Sort: q.Sort})
+		assert.NoError(t, err)
+
+		var seen []entity.Product
+		response, err := productDB.FindByQuery(q)
+		assert.NoError(t, err)
+		seen = append(seen, response.Products...)
+		for response.HasMore {
+			cursor := NewCursor(response.Products[len(response.Products)-1], desc)
+			q.After = &cursor
+
+			// inserting before the cursor must not shift the next pages
+			older, _ := entity.NewProduct("Late", price)
+			older.CreatedAt = start.Add(-time.Hour)
+			if desc {
+				older.CreatedAt = start.Add(time.Hour)
+			}
+			assert.NoError(t, productDB.Create(older))
+
+			response, err = productDB.FindByQuery(q)
+			assert.NoError(t, err)
+			assert.Zero(t, response.Page)
+			seen = append(seen, response.Products...)
+		}
+
+		assert.Len(t, seen, 7)
+		for i := range seen {
+			assert.Equal(t, all.Products[i].ID, seen[i].ID)
+		}
+		db.Where("name = ?", "Late").Delete(&entity.Product{})
+	}
+}
+
+func TestProductQuery_Keyset(t *testing.T) {
+	assert.True(t, ProductQuery{}.Keyset())
+	assert.True(t, ProductQuery{Sort: []SortKey{{Field: "created_at", Desc: true}}}.Keyset())
+	assert.False(t, ProductQuery{Sort: []SortKey{{Field: "price"}}}.Keyset())
+	assert.False(t, ProductQuery{Sort: []SortKey{{Field: "created_at"}, {Field: "name"}}}.Keyset())
+}
+
+func TestExportProducts(t *testing.T) {
+	db := NewTestDB(t)
+	productDB := NewProduct(db)
+
+	for _, name := range []string{"Desk", "Chair", "Lamp", "Desk lamp"} {
+		product, err := entity.NewProduct(name, price)
+		assert.NoError(t, err)
+		assert.NoError(t, productDB.Create(product))
+	}
+	trashed, _ := entity.NewProduct("Old desk", price)
+	assert.NoError(t, productDB.Create(trashed))
+	assert.NoError(t, productDB.Delete(trashed.ID.String(), trashed.Version))
+
+	var names []string
+	err := productDB.Export(ProductQuery{NameContains: "desk", Sort: []SortKey{{Field: "name"}}, Limit: 1}, func(product *entity.Product) error {
+		names = append(names, product.Name)
+		assert.Equal(t, price, product.Price)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Desk", "Desk lamp"}, names)
+
+	stop := errors.New("stop")
+	calls := 0
+	err = productDB.Export(ProductQuery{}, func(*entity.Product) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// searchTerms splits q into lowercase words. Everything but letters and digits
+// is a separator, so the terms are safe to embed in any full-text syntax.
+func searchTerms(q string) []string {
+	return strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// Search returns the products whose name has every word of q, most relevant
+// first. Words match as prefixes, so "key" finds "Keyboard". It uses the
+// full-text index of the backend, or LIKE on schemas not built by the
+// migrations.
+func (p *Product) Search(q string, page, limit int) (ProductResponse, error) {
+	terms := searchTerms(q)
+	if len(terms) == 0 {
+		return ProductResponse{Page: 1, Limit: limit, Products: []entity.Product{}}, nil
+	}
+
+	var match, rank func(*gorm.DB, []string) *gorm.DB
+	switch {
+	case p.DB.Dialector.Name() == "sqlite" && p.DB.Migrator().HasTable("products_fts"):
+		match, rank = matchFTS5, rankFTS5
+	case p.DB.Dialector.Name() == "postgres":
+		match, rank = matchPostgres, rankPostgres
+	case p.DB.Dialector.Name() == "mysql":
+		match, rank = matchMySQL, rankMySQL
+	default:
+		match, rank = matchLike, rankLike
+	}
+
+	count := match(p.DB.Model(&entity.Product{}), terms)
+	find := rank(match(p.DB.Preload("Categories"), terms), terms).Order("products.created_at asc")
+	return paginateProducts(count, find, page, limit)
+}
+
+func ftsQuery(terms []string) string {
+	match := make([]string, len(terms))
+	for i, term := range terms {
+		match[i] = `"` + term + `"*`
+	}
+	return strings.Join(match, " ")
+}
+
+func matchFTS5(query *gorm.DB, terms []string) *gorm.DB {
+	return query.Where("products.id IN (SELECT id FROM products_fts WHERE products_fts MATCH ?)", ftsQuery(terms))
+}
+
+// rankFTS5 joins the index back, since bm25 is only available on the rows of
+// a MATCH.
+func rankFTS5(query *gorm.DB, terms []string) *gorm.DB {
+	return query.Select("products.*").
+		Joins("JOIN products_fts ON products_fts.id = products.id").
+		Where("products_fts MATCH ?", ftsQuery(terms)).
+		Order("bm25(products_fts)")
+}
+
+func tsQuery(terms []string) string {
+	match := make([]string, len(terms))
+	for i, term := range terms {
+		match[i] = term + ":*"
+	}
+	return strings.Join(match, " & ")
+}
+
+func matchPostgres(query *gorm.DB, terms []string) *gorm.DB {
+	return query.Where("to_tsvector('simple', name) @@ to_tsquery('simple', ?)", tsQuery(terms))
+}
+
+func rankPostgres(query *gorm.DB, terms []string) *gorm.DB {
+	return query.Order(clause.OrderBy{Expression: clause.Expr{
+		SQL:                "ts_rank(to_tsvector('simple', name), to_tsquery('simple', ?)) DESC",
+		Vars:               []interface{}{tsQuery(terms)},
+		WithoutParentheses: true,
+	}})
+}
+
+func booleanQuery(terms []string) string {
+	match := make([]string, len(terms))
+	for i, term := range terms {
+		match[i] = "+" + term + "*"
+	}
+	return strings.Join(match, " ")
+}
+
+func matchMySQL(query *gorm.DB, terms []string) *gorm.DB {
+	return query.Where("MATCH (name) AGAINST (? IN BOOLEAN MODE)", booleanQuery(terms))
+}
+
+func rankMySQL(query *gorm.DB, terms []string) *gorm.DB {
+	return query.Order(clause.OrderBy{Expression: clause.Expr{
+		SQL:                "MATCH (name) AGAINST (? IN BOOLEAN MODE) DESC",
+		Vars:               []interface{}{booleanQuery(terms)},
+		WithoutParentheses: true,
+	}})
+}
+
+func matchLike(query *gorm.DB, terms []string) *gorm.DB {
+	for _, term := range terms {
+		query = query.Where("LOWER(name) LIKE ?", "%"+term+"%")
+	}
+	return query
+}
+
+// rankLike has no relevance score to go by, so it ranks shorter names first:
+// they are the ones the words cover best.
+func rankLike(query *gorm.DB, _ []string) *gorm.DB {
+	return query.Order("LENGTH(name)")
+}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/database/migrations"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// newMigratedTestDB builds the schema through the migrations, which is where
+// the search index lives.
+func newMigratedTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	if _, err = migrations.Up(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestSearchTerms(t *testing.T) {
+	assert.Equal(t, []string{"mechanical", "keyboard", "87"}, searchTerms(` Mechanical "keyboard"* (87%)`))
+	assert.Empty(t, searchTerms(" *-'\" "))
+}
+
+func TestSearchProducts(t *testing.T) {
+	db := newMigratedTestDB(t)
+	productDB := NewProduct(db)
+
+	for _, n := range []string{"Mechanical keyboard with a very long name", "Keyboard", "Wireless mouse", "Mouse pad"} {
+		product, err := entity.NewProduct(n, price)
+		assert.NoError(t, err)
+		assert.NoError(t, productDB.Create(product))
+	}
+
+	response, err := productDB.Search("keyboard", 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, response.Products, 2)
+	assert.Equal(t, "Keyboard", response.Products[0].Name)
+
+	response, err = productDB.Search("MOUSE wire", 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, response.Products, 1)
+	assert.Equal(t, "Wireless mouse", response.Products[0].Name)
+
+	response, err = productDB.Search("mouse", 1, 1)
+	assert.NoError(t, err)
+	assert.Len(t, response.Products, 1)
+	assert.Equal(t, 2, response.Total)
+	assert.Equal(t, 2, response.TotalPages)
+
+	response, err = productDB.Search("monitor", 0, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, response.Products)
+
+	response, err = productDB.Search("!!", 0, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, response.Products)
+}
+
+func TestSearchProducts_FollowsUpdatesAndDeletes(t *testing.T) {
+	db := newMigratedTestDB(t)
+	productDB := NewProduct(db)
+
+	product, _ := entity.NewProduct("Keyboard", price)
+	assert.NoError(t, productDB.Create(product))
+
+	product.Name = "Monitor"
+	assert.NoError(t, productDB.Update(product))
+
+	response, err := productDB.Search("keyboard", 0, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, response.Products)
+
+	response, err = productDB.Search("monitor", 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, response.Products, 1)
+
+	assert.NoError(t, productDB.Delete(product.ID.String(), product.Version))
+	response, err = productDB.Search("monitor", 0, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, response.Products)
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"time"
+
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	"gorm.io/gorm"
+)
+
+type RefreshToken struct {
+	DB *gorm.DB
+}
+
+func NewRefreshToken(db *gorm.DB) *RefreshToken {
+	return &RefreshToken{DB: db}
+}
+
+func (t *RefreshToken) Create(token *entity.RefreshToken) error {
+	return t.DB.Create(token).Error
+}
+
+func (t *RefreshToken) FindByHash(hash string) (*entity.RefreshToken, error) {
+	var token entity.RefreshToken
+
+	err := t.DB.Where("token_hash = ?", hash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// Rotate revokes the old token and stores its replacement in one transaction.
+// It fails with entity.ErrRefreshTokenRevoked when the old token was revoked
+// concurrently, so the same refresh token can never be exchanged twice.
+func (t *RefreshToken) Rotate(old, replacement *entity.RefreshToken) error {
+	return t.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", old.ID).
+			Update("revoked_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return entity.ErrRefreshTokenRevoked
+		}
+
+		return tx.Create(replacement).Error
+	})
+}
+
+func (t *RefreshToken) Revoke(id string) error {
+	return t.DB.Model(&entity.RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (t *RefreshToken) RevokeAllForUser(userID string) error {
+	return t.DB.Model(&entity.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	pkgEntity "github.com/sallescosta/user-and-products-manager/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func newTokenTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+
+	if err := db.AutoMigrate(&entity.RefreshToken{}, &entity.RevokedToken{}); err != nil {
+		t.Error(err)
+	}
+
+	return db
+}
+
+func TestRotateRefreshToken(t *testing.T) {
+	db := newTokenTestDB(t)
+	tokenDB := NewRefreshToken(db)
+	userID := pkgEntity.NewID()
+
+	old, plain, err := entity.NewRefreshToken(userID, time.Hour)
+	assert.NoError(t, err)
+	assert.NoError(t, tokenDB.Create(old))
+
+	found, err := tokenDB.FindByHash(entity.HashToken(plain))
+	assert.NoError(t, err)
+	assert.Equal(t, old.ID, found.ID)
+
+	replacement, _, err := entity.NewRefreshToken(userID, time.Hour)
+	assert.NoError(t, err)
+	assert.NoError(t, tokenDB.Rotate(found, replacement))
+
+	found, err = tokenDB.FindByHash(entity.HashToken(plain))
+	assert.NoError(t, err)
+	assert.Equal(t, entity.ErrRefreshTokenRevoked, found.Validate(time.Now()))
+
+	another, _, err := entity.NewRefreshToken(userID, time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.ErrRefreshTokenRevoked, tokenDB.Rotate(found, another))
+}
+
+func TestRevokeAllRefreshTokensForUser(t *testing.T) {
+	db := newTokenTestDB(t)
+	tokenDB := NewRefreshToken(db)
+	userID := pkgEntity.NewID()
+
+	first, firstPlain, _ := entity.NewRefreshToken(userID, time.Hour)
+	second, secondPlain, _ := entity.NewRefreshToken(userID, time.Hour)
+	assert.NoError(t, tokenDB.Create(first))
+	assert.NoError(t, tokenDB.Create(second))
+
+	assert.NoError(t, tokenDB.RevokeAllForUser(userID.String()))
+
+	for _, plain := range []string{firstPlain, secondPlain} {
+		found, err := tokenDB.FindByHash(entity.HashToken(plain))
+		assert.NoError(t, err)
+		assert.NotNil(t, found.RevokedAt)
+	}
+}
+
+func TestRevokeAccessToken(t *testing.T) {
+	db := newTokenTestDB(t)
+	revokedDB := NewRevokedToken(db)
+
+	revoked, err := revokedDB.IsRevoked("some-jti")
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	assert.NoError(t, revokedDB.Revoke("some-jti", time.Now().Add(time.Hour)))
+	assert.NoError(t, revokedDB.Revoke("some-jti", time.Now().Add(time.Hour)))
+
+	revoked, err = revokedDB.IsRevoked("some-jti")
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+}
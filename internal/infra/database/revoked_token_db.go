@@ -0,0 +1,34 @@
+package database
+
+import (
+	"time"
+
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RevokedToken struct {
+	DB *gorm.DB
+}
+
+func NewRevokedToken(db *gorm.DB) *RevokedToken {
+	return &RevokedToken{DB: db}
+}
+
+// Revoke adds the access token to the revocation list. Entries whose token has
+// expired anyway are dropped on the way, keeping the list short.
+func (t *RevokedToken) Revoke(jti string, expiresAt time.Time) error {
+	if err := t.DB.Where("expires_at < ?", time.Now()).Delete(&entity.RevokedToken{}).Error; err != nil {
+		return err
+	}
+
+	token := entity.RevokedToken{JTI: jti, ExpiresAt: expiresAt}
+	return t.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&token).Error
+}
+
+func (t *RevokedToken) IsRevoked(jti string) (bool, error) {
+	var count int64
+	err := t.DB.Model(&entity.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error
+	return count > 0, err
+}
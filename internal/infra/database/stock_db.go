@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	"gorm.io/gorm"
+)
+
+type Stock struct {
+	DB *gorm.DB
+}
+
+func NewStock(db *gorm.DB) *Stock {
+	return &Stock{DB: db}
+}
+
+// WithContext returns the repository bound to ctx.
+func (s *Stock) WithContext(ctx context.Context) StockInterface {
+	return &Stock{DB: s.DB.WithContext(ctx)}
+}
+
+// Record applies the movement to the on-hand quantity of its product, appends
+// it to the ledger and audits it, in one transaction. The quantity is changed with a
+// single conditional UPDATE, so concurrent movements can never take the stock
+// below zero unless allowNegative is set.
+func (s *Stock) Record(movement *entity.StockMovement, allowNegative bool) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&entity.Product{}).Where("id = ?", movement.ProductID)
+		if !allowNegative {
+			query = query.Where("quantity + ? >= 0", movement.Quantity)
+		}
+
+		result := query.UpdateColumn("quantity", gorm.Expr("quantity + ?", movement.Quantity))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			var product entity.Product
+			if err := tx.Select("id").First(&product, "id = ?", movement.ProductID).Error; err != nil {
+				return err
+			}
+			return entity.ErrInsufficientStock
+		}
+		if err := touchProduct(tx, movement.ProductID.String()); err != nil {
+			return err
+		}
+
+		var product entity.Product
+		if err := tx.Select("quantity").First(&product, "id = ?", movement.ProductID).Error; err != nil {
+			return err
+		}
+		movement.Balance = product.Quantity
+
+		return recordMovement(tx, movement)
+	})
+}
+
+// recordMovement appends movement to the ledger and audits it, within tx.
+func recordMovement(tx *gorm.DB, movement *entity.StockMovement) error {
+	if err := tx.Create(movement).Error; err != nil {
+		return err
+	}
+	entry := entity.NewAuditEntry("stock", movement.ID, entity.AuditCreate, nil, movement.AuditFields())
+	entry.ProductID = &movement.ProductID
+	return recordAudit(tx, entry)
+}
+
+func (s *Stock) OnHand(productID string) (int, error) {
+	var product entity.Product
+	err := s.DB.Select("quantity").First(&product, "id = ?", productID).Error
+	return product.Quantity, err
+}
+
+// Movements lists the ledger of a product, newest first, without the
+// movements of its variants.
+func (s *Stock) Movements(productID string, page, limit int) ([]entity.StockMovement, error) {
+	if _, err := s.OnHand(productID); err != nil {
+		return nil, err
+	}
+	return s.movements(s.DB.Where("product_id = ? AND variant_id IS NULL", productID), page, limit)
+}
+
+// VariantMovements lists the ledger of a variant of the product, newest
+// first.
+func (s *Stock) VariantMovements(productID, variantID string, page, limit int) ([]entity.StockMovement, error) {
+	if err := s.DB.Select("id").First(&entity.Variant{}, "id = ? AND product_id = ?", variantID, productID).Error; err != nil {
+		return nil, err
+	}
+	return s.movements(s.DB.Where("product_id = ? AND variant_id = ?", productID, variantID), page, limit)
+}
+
+func (s *Stock) movements(query *gorm.DB, page, limit int) ([]entity.StockMovement, error) {
+	var movements []entity.StockMovement
+	query = query.Order("created_at desc")
+	if page != 0 && limit != 0 {
+		query = query.Limit(limit).Offset((page - 1) * limit)
+	}
+	err := query.Find(&movements).Error
+	return movements, err
+}
@@ -0,0 +1,94 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	pkgEntity "github.com/sallescosta/user-and-products-manager/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestRecordStockMovements(t *testing.T) {
+	db := NewTestDB(t)
+	stockDB := NewStock(db)
+	userID := pkgEntity.NewID()
+
+	product, _ := entity.NewProduct(name, price)
+	assert.NoError(t, NewProduct(db).Create(product))
+
+	receipt, _ := entity.NewStockMovement(product.ID, userID, entity.MovementReceipt, 10, "")
+	assert.NoError(t, stockDB.Record(receipt, false))
+	assert.Equal(t, 10, receipt.Balance)
+
+	sale, _ := entity.NewStockMovement(product.ID, userID, entity.MovementSale, 4, "")
+	assert.NoError(t, stockDB.Record(sale, false))
+	assert.Equal(t, 6, sale.Balance)
+
+	tooMuch, _ := entity.NewStockMovement(product.ID, userID, entity.MovementSale, 7, "")
+	assert.Equal(t, entity.ErrInsufficientStock, stockDB.Record(tooMuch, false))
+
+	onHand, err := stockDB.OnHand(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, 6, onHand)
+
+	assert.NoError(t, stockDB.Record(tooMuch, true))
+	onHand, err = stockDB.OnHand(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, -1, onHand)
+
+	movements, err := stockDB.Movements(product.ID.String(), 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, movements, 3)
+}
+
+func TestRecordStockMovementBumpsVersion(t *testing.T) {
+	db := NewTestDB(t)
+	productDB := NewProduct(db)
+
+	product, _ := entity.NewProduct(name, price)
+	assert.NoError(t, productDB.Create(product))
+
+	receipt, _ := entity.NewStockMovement(product.ID, pkgEntity.NewID(), entity.MovementReceipt, 10, "")
+	assert.NoError(t, NewStock(db).Record(receipt, false))
+
+	found, err := productDB.FindById(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, product.Version+1, found.Version)
+
+	// a refused movement leaves it alone
+	tooMuch, _ := entity.NewStockMovement(product.ID, pkgEntity.NewID(), entity.MovementSale, 11, "")
+	assert.Equal(t, entity.ErrInsufficientStock, NewStock(db).Record(tooMuch, false))
+
+	found, err = productDB.FindById(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, product.Version+1, found.Version)
+}
+
+func TestRecordStockMovementUnknownProduct(t *testing.T) {
+	db := NewTestDB(t)
+	stockDB := NewStock(db)
+
+	receipt, _ := entity.NewStockMovement(pkgEntity.NewID(), pkgEntity.NewID(), entity.MovementReceipt, 10, "")
+	assert.ErrorIs(t, stockDB.Record(receipt, false), gorm.ErrRecordNotFound)
+}
+
+func TestUpdateProductKeepsQuantity(t *testing.T) {
+	db := NewTestDB(t)
+	productDB := NewProduct(db)
+
+	product, _ := entity.NewProduct(name, price)
+	assert.NoError(t, productDB.Create(product))
+
+	receipt, _ := entity.NewStockMovement(product.ID, pkgEntity.NewID(), entity.MovementReceipt, 5, "")
+	assert.NoError(t, NewStock(db).Record(receipt, false))
+	product.Version++
+
+	product.Quantity = 0
+	product.Name = "Produto 2"
+	assert.NoError(t, productDB.Update(product))
+
+	found, err := productDB.FindById(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, 5, found.Quantity)
+}
@@ -1,6 +1,8 @@
 package database
 
 import (
+	"database/sql"
+
 	"github.com/sallescosta/user-and-products-manager/internal/entity"
 	"gorm.io/gorm"
 )
@@ -13,14 +15,63 @@ func NewUser(db *gorm.DB) *User {
 	return &User{DB: db}
 }
 
+// checkEmail verifies, within tx, that no other user has the email of user.
+// The unique index on the email backs it up against concurrent writes.
+func checkEmail(tx *gorm.DB, user *entity.User) error {
+	var taken int64
+	if err := tx.Model(&entity.User{}).Where("email = ? AND id <> ?", user.Email, user.ID).Count(&taken).Error; err != nil {
+		return err
+	}
+	if taken > 0 {
+		return entity.ErrEmailAlreadyExists
+	}
+	return nil
+}
+
 func (u *User) Create(user *entity.User) error {
-	return u.DB.Create(user).Error
+	return u.DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkEmail(tx, user); err != nil {
+			return err
+		}
+		return tx.Create(user).Error
+	})
 }
 
+// Register creates user, as the admin when there is no user yet. Counting and
+// inserting share a serializable transaction, so that two concurrent first
+// signups cannot both become admins: one of them fails instead.
+func (u *User) Register(user *entity.User) error {
+	return u.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&entity.User{}).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			user.Role = entity.RoleAdmin
+		}
+		if err := checkEmail(tx, user); err != nil {
+			return err
+		}
+		return tx.Create(user).Error
+	}, &sql.TxOptions{Isolation: sql.LevelSerializable})
+}
+
+// FindByEmail finds the user of email, however it is typed.
 func (u *User) FindByEmail(email string) (*entity.User, error) {
 	var user entity.User
 
-	err := u.DB.Where("email = ?", email).First(&user).Error
+	err := u.DB.Where("email = ?", entity.NormalizeEmail(email)).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (u *User) FindById(id string) (*entity.User, error) {
+	var user entity.User
+
+	err := u.DB.First(&user, "id = ?", id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -28,6 +79,24 @@ func (u *User) FindByEmail(email string) (*entity.User, error) {
 	return &user, nil
 }
 
+func (u *User) Update(user *entity.User) error {
+	return u.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("id").First(&entity.User{}, "id = ?", user.ID).Error; err != nil {
+			return err
+		}
+		if err := checkEmail(tx, user); err != nil {
+			return err
+		}
+		return tx.Save(user).Error
+	})
+}
+
+func (u *User) Count() (int64, error) {
+	var count int64
+	err := u.DB.Model(&entity.User{}).Count(&count).Error
+	return count, err
+}
+
 func (u *User) GetAllUsers() ([]entity.User, error) {
 	var usersList []entity.User
 
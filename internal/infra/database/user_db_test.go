@@ -1,11 +1,14 @@
 package database
 
 import (
+	"fmt"
+	"path/filepath"
+	"sync"
 	"testing"
 
+	"github.com/glebarez/sqlite"
 	"github.com/sallescosta/user-and-products-manager/internal/entity"
 	"github.com/stretchr/testify/assert"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
@@ -53,3 +56,89 @@ func TestFindByEmail(t *testing.T) {
 	assert.Equal(t, userFound.Email, user.Email)
 	assert.NotNil(t, userFound.Password)
 }
+
+func TestUpdateUserRole(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+
+	db.AutoMigrate(&entity.User{})
+	user, _ := entity.NewUser("John", "john@gmail.com", "123456")
+
+	userDB := NewUser(db)
+
+	err = userDB.Create(user)
+	assert.Nil(t, err)
+
+	count, err := userDB.Count()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+
+	user.Role = entity.RoleEditor
+	err = userDB.Update(user)
+	assert.Nil(t, err)
+
+	userFound, err := userDB.FindById(user.ID.String())
+	assert.Nil(t, err)
+	assert.Equal(t, entity.RoleEditor, userFound.Role)
+}
+
+func TestCreateUser_UniqueEmail(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+
+	db.AutoMigrate(&entity.User{})
+	userDB := NewUser(db)
+
+	john, _ := entity.NewUser("John", "john@gmail.com", "123456")
+	assert.Nil(t, userDB.Create(john))
+
+	other, _ := entity.NewUser("Other John", " John@GMAIL.com", "123456")
+	assert.Equal(t, entity.ErrEmailAlreadyExists, userDB.Create(other))
+
+	found, err := userDB.FindByEmail("JOHN@gmail.com ")
+	assert.Nil(t, err)
+	assert.Equal(t, john.ID, found.ID)
+
+	jane, _ := entity.NewUser("Jane", "jane@gmail.com", "123456")
+	assert.Nil(t, userDB.Create(jane))
+	jane.Email = john.Email
+	assert.Equal(t, entity.ErrEmailAlreadyExists, userDB.Update(jane))
+
+	// the index backs the check up
+	other.Email = john.Email
+	assert.Error(t, db.Create(other).Error)
+}
+
+func TestRegisterFirstAdmin(t *testing.T) {
+	// a file, since every connection to :memory: is a different database
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "users.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.AutoMigrate(&entity.User{})
+	userDB := NewUser(db)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			user, _ := entity.NewUser("John", fmt.Sprintf("john%d@gmail.com", i), "123456")
+			userDB.Register(user)
+		}(i)
+	}
+	wg.Wait()
+
+	var admins int64
+	assert.Nil(t, db.Model(&entity.User{}).Where("role = ?", entity.RoleAdmin).Count(&admins).Error)
+	assert.Equal(t, int64(1), admins)
+
+	jane, _ := entity.NewUser("Jane", "jane@gmail.com", "123456")
+	assert.Nil(t, userDB.Register(jane))
+	assert.Equal(t, entity.RoleViewer, jane.Role)
+}
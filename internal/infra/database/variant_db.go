@@ -0,0 +1,184 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	entityPkg "github.com/sallescosta/user-and-products-manager/pkg/entity"
+	"gorm.io/gorm"
+)
+
+type Variant struct {
+	DB *gorm.DB
+}
+
+func NewVariant(db *gorm.DB) *Variant {
+	return &Variant{DB: db}
+}
+
+// WithContext returns the repository bound to ctx.
+func (v *Variant) WithContext(ctx context.Context) VariantInterface {
+	return &Variant{DB: v.DB.WithContext(ctx)}
+}
+
+// checkVariant verifies, within tx, that the product of variant exists, that
+// its price override is in the currency of the product and that no other
+// variant has its SKU. The unique index on the SKU backs the last check up
+// against concurrent writes.
+func checkVariant(tx *gorm.DB, variant *entity.Variant) error {
+	var product entity.Product
+	if err := tx.Select("id", "price_currency").First(&product, "id = ?", variant.ProductID).Error; err != nil {
+		return err
+	}
+	if variant.Price != nil && variant.Price.Currency != product.Price.Currency {
+		return entityPkg.ErrCurrencyMismatch
+	}
+
+	var taken int64
+	if err := tx.Model(&entity.Variant{}).Where("sku = ? AND id <> ?", variant.SKU, variant.ID).Count(&taken).Error; err != nil {
+		return err
+	}
+	if taken > 0 {
+		return entity.ErrSKUAlreadyExists
+	}
+	return nil
+}
+
+func variantAudit(tx *gorm.DB, variant *entity.Variant, action entity.AuditAction, before, after map[string]interface{}) error {
+	entry := entity.NewAuditEntry("variant", variant.ID, action, before, after)
+	entry.ProductID = &variant.ProductID
+	return recordAudit(tx, entry)
+}
+
+// adjustVariantStock changes the quantity of variant by delta within tx,
+// recording the change as an adjustment in the stock ledger so that the
+// quantity of a variant, like that of a product, is always the sum of its
+// movements. Variant.Quantity is set to the resulting balance. As with
+// Stock.Record, a concurrent change that would leave the variant below zero
+// fails with ErrInsufficientStock.
+func adjustVariantStock(tx *gorm.DB, variant *entity.Variant, delta int, reason string) error {
+	if delta != 0 {
+		result := tx.Model(&entity.Variant{}).Where("id = ? AND quantity + ? >= 0", variant.ID, delta).
+			UpdateColumn("quantity", gorm.Expr("quantity + ?", delta))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return entity.ErrInsufficientStock
+		}
+	}
+
+	var current entity.Variant
+	if err := tx.Select("quantity").First(&current, "id = ?", variant.ID).Error; err != nil {
+		return err
+	}
+	variant.Quantity = current.Quantity
+	if delta == 0 {
+		return nil
+	}
+
+	var userID entityPkg.ID
+	if ctx := tx.Statement.Context; ctx != nil {
+		userID, _ = entityPkg.ParseID(AuditActorFrom(ctx).UserID)
+	}
+	return recordMovement(tx, &entity.StockMovement{
+		ID:        entityPkg.NewID(),
+		ProductID: variant.ProductID,
+		VariantID: &variant.ID,
+		Type:      entity.MovementAdjustment,
+		Quantity:  delta,
+		Balance:   variant.Quantity,
+		Reason:    reason,
+		UserID:    userID,
+		CreatedAt: time.Now(),
+	})
+}
+
+func (v *Variant) Create(variant *entity.Variant) error {
+	return v.DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkVariant(tx, variant); err != nil {
+			return err
+		}
+		quantity := variant.Quantity
+		variant.Quantity = 0
+		if err := tx.Create(variant).Error; err != nil {
+			return err
+		}
+		if err := adjustVariantStock(tx, variant, quantity, "initial quantity of the variant"); err != nil {
+			return err
+		}
+		if err := touchProduct(tx, variant.ProductID.String()); err != nil {
+			return err
+		}
+		return variantAudit(tx, variant, entity.AuditCreate, nil, variant.AuditFields())
+	})
+}
+
+// FindByProduct lists the variants of a product, by SKU.
+func (v *Variant) FindByProduct(productID string) ([]entity.Variant, error) {
+	if err := v.DB.Select("id").First(&entity.Product{}, "id = ?", productID).Error; err != nil {
+		return nil, err
+	}
+
+	variants := []entity.Variant{}
+	err := v.DB.Where("product_id = ?", productID).Order("sku").Find(&variants).Error
+	return variants, err
+}
+
+func (v *Variant) FindById(productID, id string) (*entity.Variant, error) {
+	var variant entity.Variant
+	err := v.DB.First(&variant, "id = ? AND product_id = ?", id, productID).Error
+	return &variant, err
+}
+
+func (v *Variant) Update(variant *entity.Variant) error {
+	return v.DB.Transaction(func(tx *gorm.DB) error {
+		var before entity.Variant
+		if err := tx.First(&before, "id = ? AND product_id = ?", variant.ID, variant.ProductID).Error; err != nil {
+			return err
+		}
+		if err := checkVariant(tx, variant); err != nil {
+			return err
+		}
+
+		// a map, unlike a struct, also writes the price override when cleared;
+		// it is applied to a blank model since gorm copies it back into the one
+		// it updates
+		columns := map[string]interface{}{
+			"sku":            variant.SKU,
+			"attributes":     variant.Attributes,
+			"price_amount":   nil,
+			"price_currency": nil,
+		}
+		if variant.Price != nil {
+			columns["price_amount"], columns["price_currency"] = variant.Price.Amount, variant.Price.Currency
+		}
+		if err := tx.Model(&entity.Variant{ID: variant.ID}).Updates(columns).Error; err != nil {
+			return err
+		}
+		if err := adjustVariantStock(tx, variant, variant.Quantity-before.Quantity, "quantity of the variant set by hand"); err != nil {
+			return err
+		}
+		if err := touchProduct(tx, variant.ProductID.String()); err != nil {
+			return err
+		}
+		return variantAudit(tx, variant, entity.AuditUpdate, before.AuditFields(), variant.AuditFields())
+	})
+}
+
+func (v *Variant) Delete(productID, id string) error {
+	return v.DB.Transaction(func(tx *gorm.DB) error {
+		var variant entity.Variant
+		if err := tx.First(&variant, "id = ? AND product_id = ?", id, productID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&variant).Error; err != nil {
+			return err
+		}
+		if err := touchProduct(tx, productID); err != nil {
+			return err
+		}
+		return variantAudit(tx, &variant, entity.AuditDelete, variant.AuditFields(), nil)
+	})
+}
@@ -0,0 +1,113 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	pkgEntity "github.com/sallescosta/user-and-products-manager/pkg/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestVariants(t *testing.T) {
+	db := NewTestDB(t)
+	productDB := NewProduct(db)
+	variantDB := NewVariant(db)
+
+	product, _ := entity.NewProduct("T-shirt", price)
+	assert.NoError(t, productDB.Create(product))
+
+	override := pkgEntity.Money{Amount: 1599, Currency: "USD"}
+	medium, _ := entity.NewVariant(product.ID, "tee-m", entity.VariantAttributes{"size": "M"}, nil, 3)
+	large, _ := entity.NewVariant(product.ID, "tee-l", entity.VariantAttributes{"size": "L"}, &override, 1)
+	assert.NoError(t, variantDB.Create(medium))
+	assert.NoError(t, variantDB.Create(large))
+
+	// SKUs are unique across products, whatever their case
+	other, _ := entity.NewProduct("Hoodie", price)
+	assert.NoError(t, productDB.Create(other))
+	clash, _ := entity.NewVariant(other.ID, "TEE-M", nil, nil, 0)
+	assert.ErrorIs(t, variantDB.Create(clash), entity.ErrSKUAlreadyExists)
+
+	euros := pkgEntity.Money{Amount: 1599, Currency: "EUR"}
+	foreign, _ := entity.NewVariant(product.ID, "tee-xl", nil, &euros, 0)
+	assert.ErrorIs(t, variantDB.Create(foreign), pkgEntity.ErrCurrencyMismatch)
+
+	orphan, _ := entity.NewVariant(pkgEntity.NewID(), "orphan", nil, nil, 0)
+	assert.ErrorIs(t, variantDB.Create(orphan), gorm.ErrRecordNotFound)
+
+	found, err := productDB.FindById(product.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, found.Variants, 2)
+	assert.Equal(t, product.Version+2, found.Version)
+	assert.Equal(t, "TEE-L", found.Variants[0].SKU)
+	assert.Equal(t, &override, found.Variants[0].Price)
+	assert.Nil(t, found.Variants[1].Price)
+	assert.Equal(t, "M", found.Variants[1].Attributes["size"])
+
+	// the currency of the product cannot leave its variant overrides behind
+	found.Price = pkgEntity.Money{Amount: 900, Currency: "EUR"}
+	assert.ErrorIs(t, productDB.Update(found), pkgEntity.ErrCurrencyMismatch)
+
+	large.Price = nil
+	large.Quantity = 7
+	assert.NoError(t, variantDB.Update(large))
+	assert.Nil(t, large.Price)
+	updated, err := variantDB.FindById(product.ID.String(), large.ID.String())
+	assert.NoError(t, err)
+	assert.Nil(t, updated.Price)
+	assert.Equal(t, 7, updated.Quantity)
+
+	medium.SKU = "TEE-L"
+	assert.ErrorIs(t, variantDB.Update(medium), entity.ErrSKUAlreadyExists)
+
+	assert.ErrorIs(t, variantDB.Delete(other.ID.String(), large.ID.String()), gorm.ErrRecordNotFound)
+	assert.NoError(t, variantDB.Delete(product.ID.String(), large.ID.String()))
+
+	variants, err := variantDB.FindByProduct(product.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, variants, 1)
+
+	history, err := NewAudit(db).FindByQuery(AuditQuery{EntityType: "variant", EntityID: large.ID.String()})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, history.Total)
+}
+
+func TestVariantQuantityGoesThroughTheLedger(t *testing.T) {
+	db := NewTestDB(t)
+	productDB := NewProduct(db)
+	variantDB := NewVariant(db)
+	stockDB := NewStock(db)
+
+	product, _ := entity.NewProduct("T-shirt", price)
+	assert.NoError(t, productDB.Create(product))
+	medium, _ := entity.NewVariant(product.ID, "tee-m", nil, nil, 3)
+	assert.NoError(t, variantDB.Create(medium))
+	assert.Equal(t, 3, medium.Quantity)
+
+	medium.Quantity = 1
+	assert.NoError(t, variantDB.Update(medium))
+	// a change of the rest of the variant moves no stock
+	medium.SKU = "TEE-MEDIUM"
+	assert.NoError(t, variantDB.Update(medium))
+
+	movements, err := stockDB.VariantMovements(product.ID.String(), medium.ID.String(), 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, movements, 2)
+	sum := 0
+	for _, movement := range movements {
+		assert.Equal(t, entity.MovementAdjustment, movement.Type)
+		assert.Equal(t, medium.ID, *movement.VariantID)
+		sum += movement.Quantity
+	}
+	assert.Equal(t, 1, sum)
+	assert.Equal(t, 1, movements[0].Balance)
+
+	// the ledger of the product leaves its variants out
+	productMovements, err := stockDB.Movements(product.ID.String(), 0, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, productMovements)
+
+	_, err = stockDB.VariantMovements(product.ID.String(), pkgEntity.NewID().String(), 0, 0)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
@@ -0,0 +1,36 @@
+// Package jobs runs the periodic background work of the server.
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Job is one run of a background task.
+type Job func(ctx context.Context) error
+
+// Every runs job every interval until ctx is done, starting right away.
+// Failures are logged and the job is tried again on the next tick.
+func Every(ctx context.Context, name string, interval time.Duration, job Job) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := job(ctx); err != nil {
+			log.Printf("job %s: %v", name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
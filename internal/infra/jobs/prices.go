@@ -0,0 +1,21 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/sallescosta/user-and-products-manager/internal/infra/database"
+)
+
+// ApplyScheduledPrices makes the scheduled prices that are due the current
+// prices of their products.
+func ApplyScheduledPrices(priceDB database.PriceInterface) Job {
+	return func(ctx context.Context) error {
+		applied, err := priceDB.WithContext(ctx).ApplyDue(time.Now())
+		if applied > 0 {
+			log.Printf("applied %d scheduled prices", applied)
+		}
+		return err
+	}
+}
@@ -0,0 +1,39 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/sallescosta/user-and-products-manager/internal/infra/database"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/storage"
+)
+
+// PurgeTrash permanently deletes the products that have been in the trash for
+// longer than retention, along with the files of their images.
+func PurgeTrash(productDB database.ProductInterface, imageDB database.ImageInterface, store storage.Storage, retention time.Duration) Job {
+	return func(ctx context.Context) error {
+		purged, err := productDB.WithContext(ctx).Purge(time.Now().Add(-retention))
+		if err != nil {
+			return err
+		}
+		if purged > 0 {
+			log.Printf("purged %d products from the trash", purged)
+		}
+
+		// also picks up the images of products purged by an earlier run that
+		// failed halfway
+		orphans, err := imageDB.WithContext(ctx).PurgeOrphans()
+		if err != nil {
+			return err
+		}
+		for _, image := range orphans {
+			for _, key := range []string{image.Key, image.ThumbnailKey} {
+				if err = store.Delete(ctx, key); err != nil {
+					log.Printf("could not delete %s: %v", key, err)
+				}
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,59 @@
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// File writes each message to its own .eml file in Dir, which mail clients
+// open as they would a received email.
+type File struct {
+	Dir  string
+	From string
+}
+
+func NewFile(dir, from string) (*File, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &File{Dir: dir, From: from}, nil
+}
+
+func (f *File) Send(ctx context.Context, msg Message) error {
+	now := time.Now()
+	data, err := msg.format(f.From, now)
+	if err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err = rand.Read(suffix); err != nil {
+		return err
+	}
+	name := now.UTC().Format("20060102-150405.000000") + "-" + hex.EncodeToString(suffix) + ".eml"
+	return os.WriteFile(filepath.Join(f.Dir, name), data, 0o600)
+}
+
+// Log writes the recipient and subject of the messages to the server log
+// instead of sending them. Their bodies are left out, as they hold reset and
+// verification tokens that have no place in logs.
+type Log struct {
+	From string
+}
+
+func NewLog(from string) *Log {
+	return &Log{From: from}
+}
+
+func (l *Log) Send(ctx context.Context, msg Message) error {
+	if _, err := msg.format(l.From, time.Now()); err != nil {
+		return err
+	}
+	log.Printf("mail to %s: %s (body of %d bytes not logged)", msg.To, msg.Subject, len(msg.Body))
+	return nil
+}
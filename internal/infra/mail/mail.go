@@ -0,0 +1,76 @@
+// Package mail sends the emails of the API, such as password reset links.
+// SMTP delivers them for real, while File keeps them at hand for local
+// development and tests, and Log only notes who they were for.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	netmail "net/mail"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidAddress = errors.New("invalid email address")
+	ErrInvalidSubject = errors.New("invalid email subject")
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	// Send delivers the message, from the address the mailer is set up with.
+	Send(ctx context.Context, msg Message) error
+}
+
+// format renders the message as sent, headers included. Addresses and the
+// subject are checked, so that they cannot add headers of their own.
+func (m Message) format(from string, now time.Time) ([]byte, error) {
+	sender, err := netmail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAddress, from)
+	}
+	recipient, err := netmail.ParseAddress(m.To)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAddress, m.To)
+	}
+	if strings.ContainsAny(m.Subject, "\r\n") {
+		return nil, ErrInvalidSubject
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", sender)
+	fmt.Fprintf(&buf, "To: %s\r\n", recipient)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	body := quotedprintable.NewWriter(&buf)
+	if _, err = body.Write([]byte(strings.ReplaceAll(m.Body, "\n", "\r\n"))); err != nil {
+		return nil, err
+	}
+	if err = body.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// address is the bare address of an address header, as SMTP wants it.
+func address(s string) (string, error) {
+	a, err := netmail.ParseAddress(s)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidAddress, s)
+	}
+	return a.Address, nil
+}
@@ -0,0 +1,72 @@
+package mail
+
+import (
+	"context"
+	"io"
+	"log"
+	"mime/quotedprintable"
+	netmail "net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageFormat(t *testing.T) {
+	msg := Message{To: "John <john@example.com>", Subject: "Réinitialiser", Body: "Hello,\nopen https://example.com/reset?token=abc\n"}
+	data, err := msg.format("API <no-reply@example.com>", time.Now())
+	assert.NoError(t, err)
+
+	parsed, err := netmail.ReadMessage(strings.NewReader(string(data)))
+	assert.NoError(t, err)
+	assert.Equal(t, `"API" <no-reply@example.com>`, parsed.Header.Get("From"))
+	assert.Equal(t, `"John" <john@example.com>`, parsed.Header.Get("To"))
+	assert.Equal(t, "=?utf-8?q?R=C3=A9initialiser?=", parsed.Header.Get("Subject"))
+	body, err := io.ReadAll(quotedprintable.NewReader(parsed.Body))
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello,\r\nopen https://example.com/reset?token=abc\r\n", string(body))
+
+	// nothing can sneak headers in
+	_, err = Message{To: "john@example.com\r\nBcc: eve@example.com"}.format("no-reply@example.com", time.Now())
+	assert.ErrorIs(t, err, ErrInvalidAddress)
+	_, err = Message{To: "john@example.com", Subject: "Hi\r\nBcc: eve@example.com"}.format("no-reply@example.com", time.Now())
+	assert.ErrorIs(t, err, ErrInvalidSubject)
+	_, err = Message{To: "john@example.com"}.format("", time.Now())
+	assert.ErrorIs(t, err, ErrInvalidAddress)
+}
+
+func TestFile(t *testing.T) {
+	file, err := NewFile(filepath.Join(t.TempDir(), "mail"), "no-reply@example.com")
+	assert.NoError(t, err)
+
+	assert.NoError(t, file.Send(context.Background(), Message{To: "john@example.com", Subject: "First", Body: "1"}))
+	assert.NoError(t, file.Send(context.Background(), Message{To: "john@example.com", Subject: "Second", Body: "2"}))
+	assert.ErrorIs(t, file.Send(context.Background(), Message{To: "john"}), ErrInvalidAddress)
+
+	names, err := filepath.Glob(filepath.Join(file.Dir, "*.eml"))
+	assert.NoError(t, err)
+	assert.Len(t, names, 2)
+
+	content, err := os.ReadFile(names[0])
+	assert.NoError(t, err)
+	parsed, err := netmail.ReadMessage(strings.NewReader(string(content)))
+	assert.NoError(t, err)
+	assert.Equal(t, "<john@example.com>", parsed.Header.Get("To"))
+}
+
+func TestLogLeavesTheBodyOut(t *testing.T) {
+	var out strings.Builder
+	log.SetOutput(&out)
+	defer log.SetOutput(os.Stderr)
+
+	logger := NewLog("no-reply@example.com")
+	assert.NoError(t, logger.Send(context.Background(), Message{To: "john@example.com", Subject: "Reset your password", Body: "token=secret"}))
+	assert.ErrorIs(t, logger.Send(context.Background(), Message{To: "john"}), ErrInvalidAddress)
+
+	assert.Contains(t, out.String(), "john@example.com")
+	assert.Contains(t, out.String(), "Reset your password")
+	assert.NotContains(t, out.String(), "secret")
+}
@@ -0,0 +1,45 @@
+package mail
+
+import (
+	"context"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTP sends messages through an SMTP server, upgrading the connection with
+// STARTTLS when the server offers it. Credentials are only sent over TLS, or
+// to a server on localhost.
+type SMTP struct {
+	Addr string
+	From string
+	Auth smtp.Auth
+}
+
+func NewSMTP(host string, port int, username, password, from string) *SMTP {
+	s := &SMTP{Addr: net.JoinHostPort(host, strconv.Itoa(port)), From: from}
+	if username != "" {
+		s.Auth = smtp.PlainAuth("", username, password, host)
+	}
+	return s
+}
+
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	from, err := address(s.From)
+	if err != nil {
+		return err
+	}
+	to, err := address(msg.To)
+	if err != nil {
+		return err
+	}
+	data, err := msg.format(s.From, time.Now())
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(s.Addr, s.Auth, from, []string{to}, data)
+}
@@ -0,0 +1,75 @@
+package mail
+
+import (
+	"context"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSMTP accepts a single message and hands over the envelope and data it
+// was sent with.
+func fakeSMTP(t *testing.T) (addr string, received chan []string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	received = make(chan []string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		c := textproto.NewConn(conn)
+
+		var lines []string
+		_ = c.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := c.ReadLine()
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.Fields(line)[0]); cmd {
+			case "EHLO", "HELO":
+				_ = c.PrintfLine("250 localhost")
+			case "MAIL", "RCPT":
+				lines = append(lines, line)
+				_ = c.PrintfLine("250 OK")
+			case "DATA":
+				_ = c.PrintfLine("354 go ahead")
+				data, _ := c.ReadDotLines()
+				lines = append(lines, data...)
+				_ = c.PrintfLine("250 OK")
+			case "QUIT":
+				_ = c.PrintfLine("221 bye")
+				received <- lines
+				return
+			default:
+				_ = c.PrintfLine("502 not implemented")
+			}
+		}
+	}()
+	return l.Addr().String(), received
+}
+
+func TestSMTP(t *testing.T) {
+	addr, received := fakeSMTP(t)
+	sender := &SMTP{Addr: addr, From: "API <no-reply@example.com>"}
+
+	err := sender.Send(context.Background(), Message{To: "John <john@example.com>", Subject: "Reset", Body: "token"})
+	assert.NoError(t, err)
+
+	lines := <-received
+	assert.Equal(t, "MAIL FROM:<no-reply@example.com>", strings.SplitN(lines[0], " BODY", 2)[0])
+	assert.Equal(t, "RCPT TO:<john@example.com>", lines[1])
+	assert.Contains(t, lines, "Subject: Reset")
+	assert.Equal(t, "token", lines[len(lines)-1])
+
+	assert.ErrorIs(t, sender.Send(context.Background(), Message{To: "john"}), ErrInvalidAddress)
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocal(t *testing.T) {
+	ctx := context.Background()
+	local, err := NewLocal(t.TempDir(), "/media/")
+	assert.NoError(t, err)
+
+	assert.NoError(t, local.Put(ctx, "products/1/a.png", strings.NewReader("png"), "image/png"))
+	content, err := os.ReadFile(filepath.Join(local.Dir, "products", "1", "a.png"))
+	assert.NoError(t, err)
+	assert.Equal(t, "png", string(content))
+	assert.Equal(t, "/media/products/1/a.png", local.URL("products/1/a.png"))
+
+	for _, key := range []string{"", "../a.png", "products/../../a.png", "/a.png", "products//a.png", `products\a.png`} {
+		assert.ErrorIs(t, local.Put(ctx, key, strings.NewReader("x"), "image/png"), ErrInvalidKey, key)
+	}
+
+	handler := http.StripPrefix("/media", local)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/media/products/1/a.png", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "png", rec.Body.String())
+
+	// directories are not listed
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/media/products/1", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	assert.NoError(t, local.Delete(ctx, "products/1/a.png"))
+	assert.NoError(t, local.Delete(ctx, "products/1/a.png"))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/media/products/1/a.png", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestLocalMountPath(t *testing.T) {
+	for baseURL, mount := range map[string]string{
+		"/media":                        "/media",
+		"/static/media/":                "/static/media",
+		"http://localhost:8000/files":   "/files",
+		"https://cdn.example.com/media": "/media",
+		"https://cdn.example.com":       "",
+		"/":                             "",
+	} {
+		local, err := NewLocal(t.TempDir(), baseURL)
+		assert.NoError(t, err)
+		assert.Equal(t, mount, local.MountPath(), baseURL)
+	}
+}
@@ -0,0 +1,22 @@
+// Package storage keeps uploaded files, such as product images, under keys
+// like "products/<id>/<file>". Implementations also tell the URL each file is
+// served from.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrInvalidKey = errors.New("invalid storage key")
+
+type Storage interface {
+	// Put stores the content of r under key, replacing any file there.
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	// Delete removes the file under key. Deleting a missing file is not an
+	// error.
+	Delete(ctx context.Context, key string) error
+	// URL is where clients download the file under key from.
+	URL(key string) string
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/sallescosta/user-and-products-manager/internal/infra/database"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/webserver/problem"
+)
+
+type AuditHandler struct {
+	AuditDB database.AuditInterface
+}
+
+func NewAuditHandler(db database.AuditInterface) *AuditHandler {
+	return &AuditHandler{
+		AuditDB: db,
+	}
+}
+
+// GetAudit godoc
+// @Summary      List the audit log
+// @Description  List the audit entries of every change, newest first, filtered. Unknown parameters are rejected.
+// @Tags         audit
+// @Accept       json
+// @Produce      json
+// @Param        entity_type  query     string  false  "type of the changed entity, such as product"
+// @Param        entity_id    query     string  false  "ID of the changed entity" Format(uuid)
+// @Param        product_id   query     string  false  "ID of the product the changed entity is or belongs to" Format(uuid)
+// @Param        actor        query     string  false  "ID of the user who made the change" Format(uuid)
+// @Param        action       query     string  false  "create, update, delete, restore or purge"
+// @Param        request_id   query     string  false  "ID of the request that made the change"
+// @Param        from         query     string  false  "changed at or after, date or RFC 3339 timestamp"
+// @Param        to           query     string  false  "changed at or before, date or RFC 3339 timestamp"
+// @Param        page         query     string  false  "page number"
+// @Param        limit        query     string  false  "page size, capped by the configured maximum"
+// @Success      200          {object}  database.AuditResponse
+// @Failure      400          {object}  problem.Problem
+// @Failure      403          {object}  problem.Problem
+// @Failure      500          {object}  problem.Problem
+// @Router       /audit [get]
+// @Security ApiKeyAuth
+func (h *AuditHandler) GetAudit(w http.ResponseWriter, r *http.Request) {
+	query, err := database.ParseAuditQuery(r.URL.Query())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	query.Page, query.Limit = pageBounds(r, query.Page, query.Limit)
+	entries, err := h.AuditDB.FindByQuery(query)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	writeEntries(w, r, entries)
+}
+
+// GetProductHistory godoc
+// @Summary      Get the history of a product
+// @Description  List the audit entries of a product and of its variants, images and stock movements, newest first. The history outlives the product.
+// @Tags         audit
+// @Accept       json
+// @Produce      json
+// @Param        id        path      string  true   "product ID" Format(uuid)
+// @Param        page      query     string  false  "page number"
+// @Param        limit     query     string  false  "page size, capped by the configured maximum"
+// @Success      200       {object}  database.AuditResponse
+// @Failure      400       {object}  problem.Problem
+// @Failure      404       {object}  problem.Problem
+// @Failure      500       {object}  problem.Problem
+// @Router       /products/{id}/history [get]
+// @Security ApiKeyAuth
+func (h *AuditHandler) GetProductHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := idParam(r, "id")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	page, limit := pagination(r)
+	page, limit = pageBounds(r, page, limit)
+	entries, err := h.AuditDB.ProductHistory(id.String(), page, limit)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	writeEntries(w, r, entries)
+}
+
+func writeEntries(w http.ResponseWriter, r *http.Request, entries database.AuditResponse) {
+	entries.Prev, entries.Next = writePageLinks(w, r, entries.Page, entries.TotalPages)
+	writeJSON(w, http.StatusOK, entries)
+}
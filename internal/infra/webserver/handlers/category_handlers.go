@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/sallescosta/user-and-products-manager/internal/dto"
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/database"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/webserver/problem"
+	entityPkg "github.com/sallescosta/user-and-products-manager/pkg/entity"
+)
+
+type CategoryHandler struct {
+	CategoryDB database.CategoryInterface
+}
+
+func NewCategoryHandler(db database.CategoryInterface) *CategoryHandler {
+	return &CategoryHandler{
+		CategoryDB: db,
+	}
+}
+
+// CreateCategory godoc
+// @Summary      Create category
+// @Description  Create a category, optionally below a parent category
+// @Tags         categories
+// @Accept       json
+// @Produce      json
+// @Param        request     body      dto.CreateCategoryInput  true  "category request"
+// @Success      201         {object}  entity.Category
+// @Failure      400         {object}  problem.Problem
+// @Failure      500         {object}  problem.Problem
+// @Router       /categories [post]
+// @Security ApiKeyAuth
+func (h *CategoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	var input dto.CreateCategoryInput
+	if err := decodeJSON(r, &input); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	parentID, err := parseParentID(input.ParentID)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	category, err := entity.NewCategory(input.Name, parentID)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	if err = h.CategoryDB.WithContext(r.Context()).Create(category); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, category)
+}
+
+// GetCategories godoc
+// @Summary      List categories
+// @Description  Get every category arranged as a tree
+// @Tags         categories
+// @Accept       json
+// @Produce      json
+// @Success      200       {array}   entity.CategoryNode
+// @Failure      500       {object}  problem.Problem
+// @Router       /categories [get]
+// @Security ApiKeyAuth
+func (h *CategoryHandler) GetCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.CategoryDB.FindAll()
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, entity.BuildCategoryTree(categories))
+}
+
+// GetCategory godoc
+// @Summary      Get a category
+// @Description  Get a category
+// @Tags         categories
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string   true "category ID" Format(uuid)
+// @Success      200  {object}  entity.Category
+// @Failure      400  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /categories/{id} [get]
+// @Security ApiKeyAuth
+func (h *CategoryHandler) GetCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := idParam(r, "id")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	category, err := h.CategoryDB.FindById(id.String())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, category)
+}
+
+// UpdateCategory godoc
+// @Summary      Update a category
+// @Description  Rename a category or move it below another parent
+// @Tags         categories
+// @Accept       json
+// @Produce      json
+// @Param        id          path      string                   true  "category ID" Format(uuid)
+// @Param        request     body      dto.CreateCategoryInput  true  "category request"
+// @Success      200
+// @Failure      400         {object}  problem.Problem
+// @Failure      404         {object}  problem.Problem
+// @Failure      500         {object}  problem.Problem
+// @Router       /categories/{id} [put]
+// @Security ApiKeyAuth
+func (h *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := idParam(r, "id")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	var input dto.CreateCategoryInput
+	if err = decodeJSON(r, &input); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	parentID, err := parseParentID(input.ParentID)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	category, err := h.CategoryDB.FindById(id.String())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	category.Name = input.Name
+	category.ParentID = parentID
+	if err = category.Validate(); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	if err = h.CategoryDB.WithContext(r.Context()).Update(category); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// DeleteCategory godoc
+// @Summary      Delete a category
+// @Description  Delete a category without subcategories, unassigning it from its products
+// @Tags         categories
+// @Accept       json
+// @Produce      json
+// @Param        id        path      string   true  "category ID" Format(uuid)
+// @Success      200
+// @Failure      400       {object}  problem.Problem
+// @Failure      404       {object}  problem.Problem
+// @Failure      409       {object}  problem.Problem
+// @Failure      500       {object}  problem.Problem
+// @Router       /categories/{id} [delete]
+// @Security ApiKeyAuth
+func (h *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := idParam(r, "id")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	if err = h.CategoryDB.WithContext(r.Context()).Delete(id.String()); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func parseParentID(parentID *string) (*entityPkg.ID, error) {
+	if parentID == nil || *parentID == "" {
+		return nil, nil
+	}
+
+	id, err := entityPkg.ParseID(*parentID)
+	if err != nil {
+		return nil, entity.ErrInvalidId
+	}
+	return &id, nil
+}
@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sallescosta/user-and-products-manager/internal/dto"
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/database"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/webserver/problem"
+	"github.com/sallescosta/user-and-products-manager/pkg/xlsx"
+)
+
+// exportFlushEvery is how many products are exported between flushes of the
+// response, so that clients receive the file as it is written.
+const exportFlushEvery = 500
+
+var exportColumns = []string{"id", "name", "price", "currency", "quantity", "version", "created_at"}
+
+// productWriter writes products to an export in one of the formats.
+type productWriter interface {
+	Write(product *entity.Product) error
+	Flush() error
+	Close() error
+}
+
+var exportFormats = map[string]struct {
+	contentType string
+	open        func(w io.Writer) (productWriter, error)
+}{
+	"csv":   {"text/csv; charset=utf-8", newCSVExport},
+	"jsonl": {"application/x-ndjson", newJSONLinesExport},
+	"xlsx":  {"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", newXLSXExport},
+}
+
+func exportRecord(product *entity.Product) dto.ProductExport {
+	return dto.ProductExport{
+		ID:        product.ID.String(),
+		Name:      product.Name,
+		Price:     product.Price.Decimal(),
+		Currency:  product.Price.Currency,
+		Quantity:  product.Quantity,
+		Version:   product.Version,
+		CreatedAt: product.CreatedAt.UTC(),
+	}
+}
+
+// csvFormulaPrefixes are the first characters that make spreadsheets read a
+// cell as a formula.
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// csvQuoted tells whether cell starts with a quote that escapes a formula, or
+// another such quote.
+func csvQuoted(cell string) bool {
+	return len(cell) > 1 && cell[0] == '\'' && strings.ContainsRune(csvFormulaPrefixes+"'", rune(cell[1]))
+}
+
+// escapeCSVCell prefixes cell with a quote when a spreadsheet opening the
+// export would run it as a formula. Cells that look already escaped get one
+// too, so that unescapeCSVCell gives every cell back as it was.
+func escapeCSVCell(cell string) string {
+	if (cell != "" && strings.ContainsRune(csvFormulaPrefixes, rune(cell[0]))) || csvQuoted(cell) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// unescapeCSVCell undoes escapeCSVCell.
+func unescapeCSVCell(cell string) string {
+	if csvQuoted(cell) {
+		return cell[1:]
+	}
+	return cell
+}
+
+type csvExport struct {
+	w *csv.Writer
+}
+
+func newCSVExport(w io.Writer) (productWriter, error) {
+	export := &csvExport{w: csv.NewWriter(w)}
+	return export, export.w.Write(exportColumns)
+}
+
+func (e *csvExport) Write(product *entity.Product) error {
+	record := exportRecord(product)
+	cells := []string{
+		record.ID, record.Name, record.Price, record.Currency,
+		strconv.Itoa(record.Quantity), strconv.Itoa(record.Version), record.CreatedAt.Format(time.RFC3339),
+	}
+	for i, cell := range cells {
+		cells[i] = escapeCSVCell(cell)
+	}
+	return e.w.Write(cells)
+}
+
+func (e *csvExport) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvExport) Close() error {
+	return e.Flush()
+}
+
+// jsonLinesExport writes the products in the format POST /products/import
+// reads back.
+type jsonLinesExport struct {
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func newJSONLinesExport(w io.Writer) (productWriter, error) {
+	buf := bufio.NewWriter(w)
+	return &jsonLinesExport{buf: buf, enc: json.NewEncoder(buf)}, nil
+}
+
+func (e *jsonLinesExport) Write(product *entity.Product) error {
+	return e.enc.Encode(exportRecord(product))
+}
+
+func (e *jsonLinesExport) Flush() error {
+	return e.buf.Flush()
+}
+
+func (e *jsonLinesExport) Close() error {
+	return e.Flush()
+}
+
+type xlsxExport struct {
+	w *xlsx.Writer
+}
+
+func newXLSXExport(w io.Writer) (productWriter, error) {
+	sheet, err := xlsx.NewWriter(w, "Products")
+	if err != nil {
+		return nil, err
+	}
+	header := make([]interface{}, len(exportColumns))
+	for i, column := range exportColumns {
+		header[i] = column
+	}
+	return &xlsxExport{w: sheet}, sheet.WriteRow(header...)
+}
+
+func (e *xlsxExport) Write(product *entity.Product) error {
+	record := exportRecord(product)
+	return e.w.WriteRow(record.ID, record.Name, xlsx.Number(record.Price), record.Currency,
+		record.Quantity, record.Version, record.CreatedAt.Format(time.RFC3339))
+}
+
+func (e *xlsxExport) Flush() error {
+	return e.w.Flush()
+}
+
+func (e *xlsxExport) Close() error {
+	return e.w.Close()
+}
+
+// ExportProducts godoc
+// @Summary      Export products
+// @Description  Download the products as CSV, JSON Lines or an Excel workbook, streamed as they are read. Takes the filters and sort of GET /products, but no pagination: every matching product is exported.
+// @Tags         products
+// @Produce      text/csv
+// @Produce      application/x-ndjson
+// @Produce      application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param        format        query     string  false  "csv (default), jsonl or xlsx"
+// @Param        sort          query     string  false  "comma separated fields among name, price, quantity and created_at, descending when prefixed with -"
+// @Param        category      query     string  false  "category ID, includes its subcategories" Format(uuid)
+// @Param        name          query     string  false  "name contains, case insensitive"
+// @Param        price_min     query     string  false  "minimum price, as a decimal"
+// @Param        price_max     query     string  false  "maximum price, as a decimal"
+// @Param        currency      query     string  false  "price currency, USD when a price bound is given without it"
+// @Param        created_from  query     string  false  "created at or after, date or RFC 3339 timestamp"
+// @Param        created_to    query     string  false  "created at or before, date or RFC 3339 timestamp"
+// @Success      200           {file}    file
+// @Header       200           {string}  Content-Disposition  "attachment; filename=products-<time>.<format>"
+// @Failure      400           {object}  problem.Problem
+// @Failure      500           {object}  problem.Problem
+// @Router       /products/export [get]
+// @Security ApiKeyAuth
+func (h *ProductHandler) ExportProducts(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+	name := values.Get("format")
+	if name == "" {
+		name = "csv"
+	}
+	format, ok := exportFormats[name]
+	if !ok {
+		problem.Error(w, r, fmt.Errorf("%w: format must be csv, jsonl or xlsx", database.ErrInvalidFilter))
+		return
+	}
+	values.Del("format")
+
+	// the export has every matching product
+	for _, key := range []string{"page", "limit", "cursor"} {
+		if values.Has(key) {
+			problem.Error(w, r, fmt.Errorf("%w: %s", database.ErrUnknownQueryParameter, key))
+			return
+		}
+	}
+
+	query, err := database.ParseProductQuery(values)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+	if err = h.categoryFilter(&query); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	// the response starts with the first product, so that errors running the
+	// query can still be answered with a problem
+	var out productWriter
+	started := false
+	start := func() (err error) {
+		started = true
+		filename := fmt.Sprintf("products-%s.%s", time.Now().UTC().Format("20060102-150405"), name)
+		w.Header().Set("Content-Type", format.contentType)
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+		w.WriteHeader(http.StatusOK)
+		out, err = format.open(w)
+		return err
+	}
+
+	exported := 0
+	err = h.ProductDB.Export(query, func(product *entity.Product) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		if err := out.Write(product); err != nil {
+			return err
+		}
+		if exported++; exported%exportFlushEvery == 0 {
+			if err := out.Flush(); err != nil {
+				return err
+			}
+			_ = http.NewResponseController(w).Flush()
+		}
+		return nil
+	})
+	if err == nil && !started {
+		err = start()
+	}
+	if err == nil {
+		err = out.Close()
+	}
+
+	if err != nil && !started {
+		problem.Error(w, r, err)
+		return
+	}
+	if err != nil {
+		// too late for a problem: cut the connection, so that the client sees
+		// the file is incomplete instead of taking it for the whole catalog
+		log.Printf("%s %s: export stopped after %d products: %v", r.Method, r.URL.Path, exported, err)
+		if conn, _, hijackErr := http.NewResponseController(w).Hijack(); hijackErr == nil {
+			conn.Close()
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	pkgEntity "github.com/sallescosta/user-and-products-manager/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSVExportCanBeImported(t *testing.T) {
+	keyboard, _ := entity.NewProduct("Keyboard, mechanical", pkgEntity.Money{Amount: 4990, Currency: "USD"})
+	keyboard.Quantity = 3
+	lamp, _ := entity.NewProduct("Lamp", pkgEntity.Money{Amount: 1200, Currency: "EUR"})
+
+	var file bytes.Buffer
+	export, err := newCSVExport(&file)
+	assert.NoError(t, err)
+	for _, product := range []*entity.Product{keyboard, lamp} {
+		assert.NoError(t, export.Write(product))
+	}
+	assert.NoError(t, export.Close())
+
+	rows, err := readCSV(&file)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 2)
+	for i, product := range []*entity.Product{keyboard, lamp} {
+		assert.NoError(t, rows[i].err)
+		assert.Equal(t, i+2, rows[i].line)
+		assert.Equal(t, product.Name, rows[i].product.Name)
+		assert.Equal(t, product.Price, rows[i].product.Price)
+		// imported products are new ones
+		assert.NotEqual(t, product.ID, rows[i].product.ID)
+	}
+}
+
+func TestCSVExportEscapesFormulas(t *testing.T) {
+	names := []string{"=HYPERLINK(\"http://example.com\")", "+1", "-1", "@SUM(A1)", "'=escaped", "''", "'Tis the season", "Plain"}
+
+	var file bytes.Buffer
+	export, err := newCSVExport(&file)
+	assert.NoError(t, err)
+	for _, name := range names {
+		product, _ := entity.NewProduct(name, pkgEntity.Money{Amount: 100, Currency: "USD"})
+		product.Quantity = -2
+		assert.NoError(t, export.Write(product))
+	}
+	assert.NoError(t, export.Close())
+
+	records, err := csv.NewReader(bytes.NewReader(file.Bytes())).ReadAll()
+	assert.NoError(t, err)
+	var cells []string
+	for _, record := range records[1:] {
+		cells = append(cells, record[1])
+		assert.Equal(t, "'-2", record[4])
+	}
+	assert.Equal(t, []string{"'=HYPERLINK(\"http://example.com\")", "'+1", "'-1", "'@SUM(A1)", "''=escaped", "'''", "'Tis the season", "Plain"}, cells)
+
+	rows, err := readCSV(&file)
+	assert.NoError(t, err)
+	assert.Len(t, rows, len(names))
+	for i, name := range names {
+		assert.NoError(t, rows[i].err)
+		assert.Equal(t, name, rows[i].product.Name)
+	}
+}
+
+func TestReadCSVUnescapesFormulas(t *testing.T) {
+	file := "name,price\n'=1+1,10\n\"'\t=tab\",5\n'Tis,5\n"
+
+	rows, err := readCSV(strings.NewReader(file))
+	assert.NoError(t, err)
+	assert.Len(t, rows, 3)
+	for _, row := range rows {
+		assert.NoError(t, row.err)
+	}
+	assert.Equal(t, "=1+1", rows[0].product.Name)
+	assert.Equal(t, pkgEntity.Money{Amount: 1000, Currency: "USD"}, rows[0].product.Price)
+	assert.Equal(t, "=tab", rows[1].product.Name)
+	assert.Equal(t, "'Tis", rows[2].product.Name)
+}
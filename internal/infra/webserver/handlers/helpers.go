@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/jwtauth"
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/webserver/problem"
+	entityPkg "github.com/sallescosta/user-and-products-manager/pkg/entity"
+	"github.com/sallescosta/user-and-products-manager/pkg/signer"
+)
+
+// decodeJSON decodes the request body into v, reporting any failure as
+// problem.ErrInvalidBody.
+func decodeJSON(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", problem.ErrInvalidBody, err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// idParam reads and validates the given URL parameter as an ID.
+func idParam(r *http.Request, name string) (entityPkg.ID, error) {
+	id := chi.URLParam(r, name)
+	if id == "" {
+		return entityPkg.ID{}, entity.ErrIDIsRequired
+	}
+
+	parsed, err := entityPkg.ParseID(id)
+	if err != nil {
+		return entityPkg.ID{}, entity.ErrInvalidId
+	}
+	return parsed, nil
+}
+
+// subject returns the ID of the authenticated user, from the "sub" claim.
+func subject(r *http.Request) (entityPkg.ID, error) {
+	_, claims, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		return entityPkg.ID{}, err
+	}
+
+	sub, _ := claims["sub"].(string)
+	id, err := entityPkg.ParseID(sub)
+	if err != nil {
+		return entityPkg.ID{}, entity.ErrInvalidId
+	}
+	return id, nil
+}
+
+// signerFrom returns the signer of opaque tokens such as cursors.
+func signerFrom(r *http.Request) *signer.Signer {
+	return r.Context().Value("Signer").(*signer.Signer)
+}
+
+// tokenLink adds token to the page as its "token" query parameter, for the
+// links sent by email. Without a page to link to, the token is given as is.
+func tokenLink(page, token string) string {
+	link, err := url.Parse(page)
+	if page == "" || err != nil {
+		return token
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link.String()
+}
+
+// etag is the entity tag of a resource at version.
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// checkIfMatch enforces the If-Match precondition that writes must carry,
+// against the current version of the resource.
+func checkIfMatch(r *http.Request, version int) error {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return problem.ErrPreconditionRequired
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag(version) {
+			return nil
+		}
+	}
+	return entity.ErrVersionMismatch
+}
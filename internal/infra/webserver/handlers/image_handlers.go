@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+
+	"github.com/sallescosta/user-and-products-manager/internal/dto"
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/database"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/storage"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/webserver/problem"
+	entityPkg "github.com/sallescosta/user-and-products-manager/pkg/entity"
+	"github.com/sallescosta/user-and-products-manager/pkg/thumbnail"
+	_ "golang.org/x/image/webp"
+)
+
+// maxImagePixels bounds the images that are decoded, so that a small file
+// declaring huge dimensions cannot exhaust the memory of the server.
+const maxImagePixels = 50_000_000
+
+type ImageHandler struct {
+	ImageDB       database.ImageInterface
+	Storage       storage.Storage
+	MaxSize       int64
+	ThumbnailSize int
+}
+
+func NewImageHandler(db database.ImageInterface, store storage.Storage, maxSize int64, thumbnailSize int) *ImageHandler {
+	return &ImageHandler{
+		ImageDB:       db,
+		Storage:       store,
+		MaxSize:       maxSize,
+		ThumbnailSize: thumbnailSize,
+	}
+}
+
+// imageURLs fills in where each of images and their thumbnails are served
+// from.
+func imageURLs(store storage.Storage, images []entity.ProductImage) {
+	for i := range images {
+		images[i].URL = store.URL(images[i].Key)
+		images[i].ThumbnailURL = store.URL(images[i].ThumbnailKey)
+	}
+}
+
+// readUpload reads the "image" file of a multipart request, of at most
+// h.MaxSize bytes.
+func (h *ImageHandler) readUpload(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return nil, problem.ErrUnsupportedMediaType
+	}
+
+	// leaves room for the headers and boundaries of the parts
+	r.Body = http.MaxBytesReader(w, r.Body, h.MaxSize+64<<10)
+	err := r.ParseMultipartForm(1 << 20)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return nil, entity.ErrImageTooLarge
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", problem.ErrInvalidBody, err)
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("image")
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, entity.ErrImageIsRequired
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", problem.ErrInvalidBody, err)
+	}
+	defer file.Close()
+
+	if header.Size > h.MaxSize {
+		return nil, entity.ErrImageTooLarge
+	}
+	return io.ReadAll(file)
+}
+
+// process checks that data is an image of a supported type, whatever the
+// client claims it is, and makes its thumbnail.
+func (h *ImageHandler) process(productID entityPkg.ID, data []byte) (*entity.ProductImage, []byte, error) {
+	if len(data) == 0 {
+		return nil, nil, entity.ErrImageIsRequired
+	}
+	contentType := http.DetectContentType(data)
+	if _, ok := entity.ImageExtensions[contentType]; !ok {
+		return nil, nil, entity.ErrUnsupportedImageType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, entity.ErrInvalidImage
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, nil, entity.ErrImageTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, entity.ErrInvalidImage
+	}
+
+	productImage, err := entity.NewProductImage(productID, contentType, int64(len(data)), config.Width, config.Height)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var thumb bytes.Buffer
+	if err = thumbnail.Encode(&thumb, thumbnail.Fit(img, h.ThumbnailSize), entity.ThumbnailType(contentType)); err != nil {
+		return nil, nil, err
+	}
+	return productImage, thumb.Bytes(), nil
+}
+
+// GetImages godoc
+// @Summary      List the images of a product
+// @Description  List the images of a product, in order
+// @Tags         images
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "product ID" Format(uuid)
+// @Success      200  {array}   entity.ProductImage
+// @Failure      400  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /products/{id}/images [get]
+// @Security ApiKeyAuth
+func (h *ImageHandler) GetImages(w http.ResponseWriter, r *http.Request) {
+	productID, err := idParam(r, "id")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	images, err := h.ImageDB.FindByProduct(productID.String())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	imageURLs(h.Storage, images)
+	writeJSON(w, http.StatusOK, images)
+}
+
+// UploadImage godoc
+// @Summary      Upload an image
+// @Description  Add an image to the end of the images of a product, from the "image" field of a multipart form. JPEG, PNG, GIF and WebP images are accepted, detected from their content. A thumbnail is made along with it.
+// @Tags         images
+// @Accept       mpfd
+// @Produce      json
+// @Param        id     path      string  true  "product ID" Format(uuid)
+// @Param        image  formData  file    true  "the image"
+// @Success      201    {object}  entity.ProductImage
+// @Failure      400    {object}  problem.Problem
+// @Failure      404    {object}  problem.Problem
+// @Failure      413    {object}  problem.Problem
+// @Failure      415    {object}  problem.Problem
+// @Failure      500    {object}  problem.Problem
+// @Router       /products/{id}/images [post]
+// @Security ApiKeyAuth
+func (h *ImageHandler) UploadImage(w http.ResponseWriter, r *http.Request) {
+	productID, err := idParam(r, "id")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	data, err := h.readUpload(w, r)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	productImage, thumb, err := h.process(productID, data)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	if err = h.Storage.Put(ctx, productImage.Key, bytes.NewReader(data), productImage.ContentType); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+	err = h.Storage.Put(ctx, productImage.ThumbnailKey, bytes.NewReader(thumb), entity.ThumbnailType(productImage.ContentType))
+	if err == nil {
+		err = h.ImageDB.WithContext(ctx).Create(productImage)
+	}
+	if err != nil {
+		h.deleteFiles(r, productImage)
+		problem.Error(w, r, err)
+		return
+	}
+
+	images := []entity.ProductImage{*productImage}
+	imageURLs(h.Storage, images)
+	writeJSON(w, http.StatusCreated, images[0])
+}
+
+// ReorderImages godoc
+// @Summary      Reorder the images
+// @Description  Put the images of a product in the order given, which must list each of them exactly once
+// @Tags         images
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                   true  "product ID" Format(uuid)
+// @Param        request  body      dto.ReorderImagesInput  true  "image IDs in their new order"
+// @Success      200      {array}   entity.ProductImage
+// @Failure      400      {object}  problem.Problem
+// @Failure      404      {object}  problem.Problem
+// @Failure      500      {object}  problem.Problem
+// @Router       /products/{id}/images/order [put]
+// @Security ApiKeyAuth
+func (h *ImageHandler) ReorderImages(w http.ResponseWriter, r *http.Request) {
+	productID, err := idParam(r, "id")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	var input dto.ReorderImagesInput
+	if err = decodeJSON(r, &input); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	images, err := h.ImageDB.WithContext(r.Context()).Reorder(productID.String(), input.IDs)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	imageURLs(h.Storage, images)
+	writeJSON(w, http.StatusOK, images)
+}
+
+// DeleteImage godoc
+// @Summary      Delete an image
+// @Description  Delete an image of a product and its thumbnail
+// @Tags         images
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string  true  "product ID" Format(uuid)
+// @Param        imageId  path      string  true  "image ID" Format(uuid)
+// @Success      200
+// @Failure      400      {object}  problem.Problem
+// @Failure      404      {object}  problem.Problem
+// @Failure      500      {object}  problem.Problem
+// @Router       /products/{id}/images/{imageId} [delete]
+// @Security ApiKeyAuth
+func (h *ImageHandler) DeleteImage(w http.ResponseWriter, r *http.Request) {
+	productID, err := idParam(r, "id")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	imageID, err := idParam(r, "imageId")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	productImage, err := h.ImageDB.FindById(productID.String(), imageID.String())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	if err = h.ImageDB.WithContext(r.Context()).Delete(productID.String(), imageID.String()); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+	h.deleteFiles(r, productImage)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// deleteFiles removes the files of productImage from the storage. Failures are
+// only logged: the image is gone either way.
+func (h *ImageHandler) deleteFiles(r *http.Request, productImage *entity.ProductImage) {
+	for _, key := range []string{productImage.Key, productImage.ThumbnailKey} {
+		if err := h.Storage.Delete(r.Context(), key); err != nil {
+			log.Printf("%s %s: could not delete %s: %v", r.Method, r.URL.Path, key, err)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/sallescosta/user-and-products-manager/internal/dto"
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/database"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/webserver/problem"
+)
+
+type ImportHandler struct {
+	ProductDB database.ProductInterface
+	BatchSize int
+	MaxSize   int64
+}
+
+func NewImportHandler(db database.ProductInterface, batchSize int, maxSize int64) *ImportHandler {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	return &ImportHandler{
+		ProductDB: db,
+		BatchSize: batchSize,
+		MaxSize:   maxSize,
+	}
+}
+
+// importRow is a row of an import: the product it describes, or why it does
+// not describe one.
+type importRow struct {
+	line    int
+	product *entity.Product
+	err     error
+}
+
+// newImportRow validates input as POST /products would.
+func newImportRow(line int, input dto.CreateProductInput) importRow {
+	price, err := parsePrice(input.Price, input.Currency)
+	if err != nil {
+		return importRow{line: line, err: err}
+	}
+	product, err := entity.NewProduct(input.Name, price)
+	return importRow{line: line, product: product, err: err}
+}
+
+// importReader reads the rows of an import in one of the formats.
+type importReader func(body io.Reader) ([]importRow, error)
+
+// importFormat picks the reader of the Content-Type of r.
+func importFormat(r *http.Request) (importReader, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		return readCSV, nil
+	case "application/x-ndjson", "application/jsonl", "application/x-jsonlines":
+		return readJSONLines, nil
+	}
+	return nil, problem.ErrUnsupportedMediaType
+}
+
+// readCSV reads a CSV file whose header names its columns: name, price and,
+// optionally, currency, in any order. The other columns of the CSV export are
+// ignored and its escaped formulas unescaped, so that it can be imported
+// again. A malformed file is rejected as
+// a whole; rows with the wrong number of fields only fail themselves.
+func readCSV(body io.Reader) ([]importRow, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: the file is empty", problem.ErrInvalidBody)
+	}
+	if err != nil {
+		return nil, readError(err)
+	}
+
+	columns := map[string]int{}
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))
+		switch column {
+		case "name", "price", "currency":
+			columns[column] = i
+		case "id", "quantity", "version", "created_at":
+		default:
+			return nil, fmt.Errorf("%w: unknown column %q", problem.ErrInvalidBody, column)
+		}
+	}
+	for _, column := range []string{"name", "price"} {
+		if _, ok := columns[column]; !ok {
+			return nil, fmt.Errorf("%w: missing column %q", problem.ErrInvalidBody, column)
+		}
+	}
+
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+
+		if err != nil && !errors.Is(err, csv.ErrFieldCount) {
+			return nil, readError(err)
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			rows = append(rows, importRow{line: line, err: fmt.Errorf("%w: expected %d fields", problem.ErrInvalidBody, len(header))})
+			continue
+		}
+
+		input := dto.CreateProductInput{
+			Name:  strings.TrimSpace(unescapeCSVCell(record[columns["name"]])),
+			Price: json.Number(strings.TrimSpace(unescapeCSVCell(record[columns["price"]]))),
+		}
+		if i, ok := columns["currency"]; ok {
+			input.Currency = strings.TrimSpace(unescapeCSVCell(record[i]))
+		}
+		rows = append(rows, newImportRow(line, input))
+	}
+}
+
+func readError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return problem.ErrImportTooLarge
+	}
+	return fmt.Errorf("%w: %v", problem.ErrInvalidBody, err)
+}
+
+// readJSONLines reads one product per line, in the body of POST /products.
+// Blank lines are skipped, and a line that is not valid JSON only fails
+// itself.
+func readJSONLines(body io.Reader) ([]importRow, error) {
+	reader := bufio.NewReader(body)
+
+	var rows []importRow
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, readError(err)
+		}
+
+		if data = bytes.TrimSpace(data); len(data) > 0 {
+			var input dto.CreateProductInput
+			if jsonErr := json.Unmarshal(data, &input); jsonErr != nil {
+				rows = append(rows, importRow{line: line, err: fmt.Errorf("%w: %v", problem.ErrInvalidBody, jsonErr)})
+			} else {
+				rows = append(rows, newImportRow(line, input))
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+	}
+}
+
+// failure fills in why the row of report failed.
+func failure(row *dto.ImportRow, err error) {
+	_, code, _ := problem.Lookup(err)
+	row.Status, row.Code, row.Error = dto.ImportFailed, code, problem.Detail(err)
+}
+
+// ImportProducts godoc
+// @Summary      Import products
+// @Description  Create products in bulk from a CSV file, with a header naming the name, price and (optional) currency columns, or from JSON Lines, one product per line as in POST /products. Each row is validated like a single product; the valid ones are saved in batches, each in its own transaction. With dry_run, rows are only validated.
+// @Tags         products
+// @Accept       text/csv
+// @Accept       application/x-ndjson
+// @Produce      json
+// @Param        dry_run  query     bool    false  "validate the rows without saving them"
+// @Param        request  body      string  true   "the products"
+// @Success      200      {object}  dto.ImportReport
+// @Failure      400      {object}  problem.Problem
+// @Failure      413      {object}  problem.Problem
+// @Failure      415      {object}  problem.Problem
+// @Failure      500      {object}  problem.Problem
+// @Router       /products/import [post]
+// @Security ApiKeyAuth
+func (h *ImportHandler) ImportProducts(w http.ResponseWriter, r *http.Request) {
+	read, err := importFormat(r)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	dryRun := false
+	if value := r.URL.Query().Get("dry_run"); value != "" {
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			problem.Error(w, r, fmt.Errorf("%w: dry_run must be a boolean", database.ErrInvalidFilter))
+			return
+		}
+	}
+
+	// every row is read and validated before any is saved, so that a
+	// malformed file imports nothing
+	rows, err := read(http.MaxBytesReader(w, r.Body, h.MaxSize))
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	report := dto.ImportReport{DryRun: dryRun, Total: len(rows), Rows: make([]dto.ImportRow, len(rows))}
+	var batch []int
+	for i, row := range rows {
+		report.Rows[i].Line = row.line
+		if row.err != nil {
+			failure(&report.Rows[i], row.err)
+			continue
+		}
+
+		report.Rows[i].ID = row.product.ID.String()
+		report.Rows[i].Status = dto.ImportValid
+		if !dryRun {
+			batch = append(batch, i)
+		}
+	}
+
+	productDB := h.ProductDB.WithContext(r.Context())
+	for start := 0; start < len(batch); start += h.BatchSize {
+		indexes := batch[start:min(start+h.BatchSize, len(batch))]
+		products := make([]*entity.Product, len(indexes))
+		for k, i := range indexes {
+			products[k] = rows[i].product
+		}
+
+		err = productDB.CreateBatch(products)
+		if _, _, ok := problem.Lookup(err); err != nil && !ok {
+			// reported to the client as an internal error, like problem.Error
+			log.Printf("%s %s: lines %d to %d: %v", r.Method, r.URL.Path, rows[indexes[0]].line, rows[indexes[len(indexes)-1]].line, err)
+		}
+		for _, i := range indexes {
+			if err != nil {
+				failure(&report.Rows[i], err)
+			} else {
+				report.Rows[i].Status = dto.ImportCreated
+			}
+		}
+	}
+
+	for _, row := range report.Rows {
+		switch row.Status {
+		case dto.ImportCreated:
+			report.Created++
+		case dto.ImportValid:
+			report.Valid++
+		default:
+			report.Failed++
+		}
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/database"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/webserver/problem"
+)
+
+// LoginThrottle slows password guessing down: every failed login blocks the
+// account and the IP address it came from for a while, longer after each
+// failure. A zero LoginThrottle lets every login through.
+type LoginThrottle struct {
+	Store   database.LoginAttemptInterface
+	Account entity.LoginLimit
+	IP      entity.LoginLimit
+}
+
+type loginKey struct {
+	key   string
+	limit entity.LoginLimit
+}
+
+func accountKey(email string) string {
+	return "account:" + entity.NormalizeEmail(email)
+}
+
+// loginKeys are the counters a login with email, on r, goes through. The IP
+// address is the one of the connection: behind a proxy, every client shares
+// the address of the proxy.
+func (t LoginThrottle) loginKeys(r *http.Request, email string) []loginKey {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return []loginKey{
+		{accountKey(email), t.Account},
+		{"ip:" + ip, t.IP},
+	}
+}
+
+// allow writes a 429 problem, with the Retry-After header, and returns false
+// when the account or the IP address of the login are blocked.
+func (t LoginThrottle) allow(w http.ResponseWriter, r *http.Request, email string) bool {
+	if t.Store == nil {
+		return true
+	}
+
+	now := time.Now()
+	var wait time.Duration
+	for _, k := range t.loginKeys(r, email) {
+		attempt, err := t.Store.Find(k.key, now)
+		if err != nil {
+			problem.Error(w, r, err)
+			return false
+		}
+		wait = max(wait, attempt.RetryAfter(now))
+	}
+	if wait == 0 {
+		return true
+	}
+
+	seconds := int(math.Ceil(wait.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	problem.Error(w, r, fmt.Errorf("%w: retry in %d seconds", entity.ErrTooManyLoginAttempts, seconds))
+	return false
+}
+
+// fail counts a failed login. The login is refused anyway, so errors are
+// only logged.
+func (t LoginThrottle) fail(r *http.Request, email string) {
+	if t.Store == nil {
+		return
+	}
+	for _, k := range t.loginKeys(r, email) {
+		if _, err := t.Store.Fail(k.key, time.Now(), k.limit); err != nil {
+			log.Printf("counting the failed login of %s: %v", k.key, err)
+		}
+	}
+}
+
+// succeed clears the failures of the account. Those of the IP address stay,
+// so that an account of their own does not let guessers start over.
+func (t LoginThrottle) succeed(email string) error {
+	if t.Store == nil {
+		return nil
+	}
+	return t.Store.Reset(accountKey(email))
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/sallescosta/user-and-products-manager/internal/infra/database"
+)
+
+// defaultMaxLimit caps page sizes when no PaginationMaxLimit is configured.
+const defaultMaxLimit = 100
+
+// pagination reads the page and limit query parameters, bounded by pageBounds.
+func pagination(r *http.Request) (int, int) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 0 {
+		page = 0
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 0 {
+		limit = 0
+	}
+
+	return pageBounds(r, page, limit)
+}
+
+// pageBounds defaults to the first page, and caps the limit to the configured
+// maximum, which is also the limit when none is asked for.
+func pageBounds(r *http.Request, page, limit int) (int, int) {
+	maxLimit, ok := r.Context().Value("PaginationMaxLimit").(int)
+	if !ok || maxLimit <= 0 {
+		maxLimit = defaultMaxLimit
+	}
+
+	if page == 0 {
+		page = 1
+	}
+	if limit == 0 || limit > maxLimit {
+		limit = maxLimit
+	}
+	return page, limit
+}
+
+// pageURL is the URL of the request with its page parameter replaced.
+func pageURL(r *http.Request, page int) string {
+	query := r.URL.Query()
+	query.Set("page", strconv.Itoa(page))
+	return r.URL.Path + "?" + query.Encode()
+}
+
+// setPageLinks fills the next and prev links of response and sends them,
+// along with the first and last pages, in an RFC 8288 Link header.
+func setPageLinks(w http.ResponseWriter, r *http.Request, response *database.ProductResponse) {
+	// paging by cursor, see setCursor
+	if response.Page == 0 {
+		return
+	}
+	response.Prev, response.Next = writePageLinks(w, r, response.Page, response.TotalPages)
+}
+
+// writePageLinks sends the Link header of page among totalPages, and returns
+// the links to the previous and next pages, empty when there is none.
+func writePageLinks(w http.ResponseWriter, r *http.Request, page, totalPages int) (prev, next string) {
+	var links []string
+	link := func(page int, rel string) string {
+		url := pageURL(r, page)
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, url, rel))
+		return url
+	}
+
+	if totalPages == 0 || page == 0 {
+		return "", ""
+	}
+	link(1, "first")
+	if page > 1 {
+		prev = link(min(page-1, totalPages), "prev")
+	}
+	if page < totalPages {
+		next = link(page+1, "next")
+	}
+	link(totalPages, "last")
+
+	w.Header().Set("Link", strings.Join(links, ", "))
+	return prev, next
+}
+
+// applyCursor reads the cursor parameter into query. A cursor fixes the
+// order, so it cannot be combined with a page nor with another sort.
+func applyCursor(r *http.Request, query *database.ProductQuery) error {
+	raw := r.URL.Query().Get("cursor")
+	if raw == "" {
+		return nil
+	}
+
+	var cursor database.Cursor
+	if err := signerFrom(r).Verify(raw, &cursor); err != nil {
+		return database.ErrInvalidCursor
+	}
+	if r.URL.Query().Has("page") {
+		return fmt.Errorf("%w: cursor and page cannot be combined", database.ErrInvalidCursor)
+	}
+	if !query.Keyset() || (len(query.Sort) > 0 && query.Descending() != cursor.Desc) {
+		return fmt.Errorf("%w: the sort cannot change while paging by cursor", database.ErrInvalidCursor)
+	}
+
+	query.Sort = []database.SortKey{{Field: "created_at", Desc: cursor.Desc}}
+	query.After = &cursor
+	return nil
+}
+
+// setCursor fills the next cursor of response, when the listing continues and
+// is ordered so that it can be paged by cursor. When paging by cursor, it
+// also sends the next and first links.
+func setCursor(w http.ResponseWriter, r *http.Request, query database.ProductQuery, response *database.ProductResponse) error {
+	if response.HasMore && query.Keyset() && len(response.Products) > 0 {
+		next, err := signerFrom(r).Sign(database.NewCursor(response.Products[len(response.Products)-1], query.Descending()))
+		if err != nil {
+			return err
+		}
+		response.NextCursor = next
+	}
+
+	if query.After == nil {
+		return nil
+	}
+
+	values := r.URL.Query()
+	values.Del("cursor")
+	if query.Descending() && values.Get("sort") == "" {
+		values.Set("sort", "desc")
+	}
+	links := []string{fmt.Sprintf(`<%s>; rel="first"`, r.URL.Path+"?"+values.Encode())}
+	if response.NextCursor != "" {
+		values.Set("cursor", response.NextCursor)
+		response.Next = r.URL.Path + "?" + values.Encode()
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, response.Next))
+	}
+	w.Header().Set("Link", strings.Join(links, ", "))
+	return nil
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/sallescosta/user-and-products-manager/internal/dto"
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/database"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/mail"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/webserver/problem"
+	"gorm.io/gorm"
+)
+
+type PasswordHandler struct {
+	UserDB          database.UserInterface
+	PasswordResetDB database.PasswordResetInterface
+	Mailer          mail.Mailer
+	// ExpiresIn is how long reset tokens can be used for.
+	ExpiresIn time.Duration
+	// ResetURL is the page of the client where users choose their new
+	// password. The token is added to it as the "token" query parameter.
+	ResetURL       string
+	PasswordPolicy *entity.PasswordPolicy
+}
+
+func NewPasswordHandler(userDB database.UserInterface, passwordResetDB database.PasswordResetInterface, mailer mail.Mailer, expiresIn time.Duration, resetURL string, passwordPolicy *entity.PasswordPolicy) *PasswordHandler {
+	return &PasswordHandler{
+		UserDB:          userDB,
+		PasswordResetDB: passwordResetDB,
+		Mailer:          mailer,
+		ExpiresIn:       expiresIn,
+		ResetURL:        resetURL,
+		PasswordPolicy:  passwordPolicy,
+	}
+}
+
+// ForgotPassword godoc
+// @Summary      Forgot password
+// @Description  Email the user a link to reset their password. The answer is the same whether the email belongs to a user or not, so that it does not tell which ones do.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request   body     dto.ForgotPasswordInput  true  "user email"
+// @Success      202
+// @Failure      400  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /users/password/forgot [post]
+func (h *PasswordHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var input dto.ForgotPasswordInput
+	if err := decodeJSON(r, &input); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	u, err := h.UserDB.FindByEmail(input.Email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	token, plain, err := entity.NewPasswordResetToken(u.ID, h.ExpiresIn)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+	if err = h.PasswordResetDB.Create(token); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	// a failure to send is not told either: it would only be for existing
+	// users
+	if err = h.Mailer.Send(r.Context(), h.resetMessage(u, plain)); err != nil {
+		log.Printf("sending the password reset email of user %s: %v", u.ID, err)
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *PasswordHandler) resetMessage(u *entity.User, token string) mail.Message {
+	return mail.Message{
+		To:      u.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"Someone asked to reset the password of your account. To choose a new one, use:\n\n"+
+			"%s\n\n"+
+			"It works once, in the next %d minutes. If you did not ask for it, ignore this email: your password stays the same.\n",
+			u.Name, tokenLink(h.ResetURL, token), int(h.ExpiresIn.Minutes())),
+	}
+}
+
+// ResetPassword godoc
+// @Summary      Reset password
+// @Description  Set a new password with the token emailed by POST /users/password/forgot. The token works once. The refresh tokens of the user are revoked, so their other sessions end once their access tokens expire.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request   body     dto.ResetPasswordInput  true  "reset token and new password"
+// @Success      204
+// @Failure      400  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /users/password/reset [post]
+func (h *PasswordHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var input dto.ResetPasswordInput
+	if err := decodeJSON(r, &input); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+	if input.Token == "" {
+		problem.Error(w, r, entity.ErrInvalidResetToken)
+		return
+	}
+
+	token, err := h.PasswordResetDB.FindByHash(entity.HashToken(input.Token))
+	if err != nil {
+		problem.Error(w, r, entity.ErrInvalidResetToken)
+		return
+	}
+	if err = token.Validate(time.Now()); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	u, err := h.UserDB.FindById(token.UserID.String())
+	if err != nil {
+		problem.Error(w, r, entity.ErrInvalidResetToken)
+		return
+	}
+	if err = h.PasswordPolicy.Check(input.Password); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+	if err = u.SetPassword(input.Password); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+	// the token came by email, which proves it is theirs as well
+	u.Verified = true
+
+	if err = h.PasswordResetDB.Consume(token, u); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
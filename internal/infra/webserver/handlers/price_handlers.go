@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/sallescosta/user-and-products-manager/internal/dto"
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/database"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/webserver/problem"
+)
+
+type PriceHandler struct {
+	PriceDB database.PriceInterface
+}
+
+func NewPriceHandler(db database.PriceInterface) *PriceHandler {
+	return &PriceHandler{
+		PriceDB: db,
+	}
+}
+
+// GetPrices godoc
+// @Summary      Get the price timeline
+// @Description  List every price of a product, past, current and scheduled, in the order they take effect
+// @Tags         prices
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "product ID" Format(uuid)
+// @Success      200  {array}   entity.ProductPrice
+// @Failure      400  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /products/{id}/prices [get]
+// @Security ApiKeyAuth
+func (h *PriceHandler) GetPrices(w http.ResponseWriter, r *http.Request) {
+	productID, err := idParam(r, "id")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	prices, err := h.PriceDB.Timeline(productID.String())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, prices)
+}
+
+// SchedulePrice godoc
+// @Summary      Schedule a price change
+// @Description  Schedule a future price of a product. It becomes the price of the product once effective_from has passed.
+// @Tags         prices
+// @Accept       json
+// @Produce      json
+// @Param        id          path      string                  true  "product ID" Format(uuid)
+// @Param        request     body      dto.SchedulePriceInput  true  "price and when it takes effect"
+// @Success      201         {object}  entity.ProductPrice
+// @Failure      400         {object}  problem.Problem
+// @Failure      404         {object}  problem.Problem
+// @Failure      409         {object}  problem.Problem
+// @Failure      500         {object}  problem.Problem
+// @Router       /products/{id}/prices [post]
+// @Security ApiKeyAuth
+func (h *PriceHandler) SchedulePrice(w http.ResponseWriter, r *http.Request) {
+	productID, err := idParam(r, "id")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	userID, err := subject(r)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	var input dto.SchedulePriceInput
+	if err = decodeJSON(r, &input); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	amount, err := parsePrice(input.Price, input.Currency)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	price, err := entity.NewScheduledPrice(productID, userID, amount, input.EffectiveFrom)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	if err = h.PriceDB.WithContext(r.Context()).Schedule(price); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, price)
+}
+
+// CancelPrice godoc
+// @Summary      Cancel a scheduled price
+// @Description  Delete a price of a product that has not taken effect yet
+// @Tags         prices
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string  true  "product ID" Format(uuid)
+// @Param        priceId  path      string  true  "price ID" Format(uuid)
+// @Success      200
+// @Failure      400      {object}  problem.Problem
+// @Failure      404      {object}  problem.Problem
+// @Failure      409      {object}  problem.Problem
+// @Failure      500      {object}  problem.Problem
+// @Router       /products/{id}/prices/{priceId} [delete]
+// @Security ApiKeyAuth
+func (h *PriceHandler) CancelPrice(w http.ResponseWriter, r *http.Request) {
+	productID, err := idParam(r, "id")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	priceID, err := idParam(r, "priceId")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	if err = h.PriceDB.WithContext(r.Context()).Cancel(productID.String(), priceID.String()); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -1,26 +1,37 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
-	"strconv"
+	"strings"
 
-	"github.com/go-chi/chi"
 	"github.com/sallescosta/user-and-products-manager/internal/entity"
 	"github.com/sallescosta/user-and-products-manager/internal/infra/database"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/storage"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/webserver/problem"
 	entityPkg "github.com/sallescosta/user-and-products-manager/pkg/entity"
-
-	"encoding/json"
+	"github.com/sallescosta/user-and-products-manager/pkg/patch"
+	"gorm.io/gorm"
 
 	"github.com/sallescosta/user-and-products-manager/internal/dto"
 )
 
 type ProductHandler struct {
-	ProductDB database.ProductInterface
+	ProductDB  database.ProductInterface
+	CategoryDB database.CategoryInterface
+	Storage    storage.Storage
 }
 
-func NewProductHandler(db database.ProductInterface) *ProductHandler {
+func NewProductHandler(db database.ProductInterface, categoryDB database.CategoryInterface, store storage.Storage) *ProductHandler {
 	return &ProductHandler{
-		ProductDB: db,
+		ProductDB:  db,
+		CategoryDB: categoryDB,
+		Storage:    store,
 	}
 }
 
@@ -32,126 +43,287 @@ func NewProductHandler(db database.ProductInterface) *ProductHandler {
 // @Produce      json
 // @Param        request     body      dto.CreateProductInput  true  "product request"
 // @Success      201
-// @Failure      500         {object}  Error
+// @Failure      400         {object}  problem.Problem
+// @Failure      500         {object}  problem.Problem
 // @Router       /products [post]
 // @Security ApiKeyAuth
 func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 	var product dto.CreateProductInput
+	if err := decodeJSON(r, &product); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
 
-	err := json.NewDecoder(r.Body).Decode(&product)
+	price, err := parsePrice(product.Price, product.Currency)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		problem.Error(w, r, err)
 		return
 	}
 
-	p, err := entity.NewProduct(product.Name, product.Price)
+	p, err := entity.NewProduct(product.Name, price)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		problem.Error(w, r, err)
 		return
 	}
 
-	err = h.ProductDB.Create(p)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+	if err = h.ProductDB.WithContext(r.Context()).Create(p); err != nil {
+		problem.Error(w, r, err)
+		return
 	}
 
 	w.WriteHeader(http.StatusCreated)
 }
 
+// parsePrice reads a decimal price in currency, the default one when empty. A
+// missing price is returned as zero so that validation reports it.
+func parsePrice(price json.Number, currency string) (entityPkg.Money, error) {
+	if currency == "" {
+		currency = entityPkg.DefaultCurrency
+	}
+
+	if price == "" {
+		return entityPkg.NewMoney(0, currency)
+	}
+	return entityPkg.ParseMoney(price.String(), currency)
+}
+
 // GetProduct godoc
 // @Summary      Get a product
 // @Description  Get a product
 // @Tags         products
 // @Accept       json
 // @Produce      json
-// @Param        id   path      string   true "product ID" Format(uuid)
+// @Param        id             path      string   true   "product ID" Format(uuid)
+// @Param        If-None-Match  header    string   false  "ETag of a cached copy"
 // @Success      200  {object}  entity.Product
-// @Failure      404
-// @Failure      500 {object} Error
+// @Header       200  {string}  ETag  "version of the product"
+// @Success      304
+// @Failure      400  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
 // @Router       /products/{id} [get]
 // @Security ApiKeyAuth
 func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
-	id := chi.URLParam(r, "id")
-	if id == "" {
-		w.WriteHeader(http.StatusBadRequest)
+	id, err := idParam(r, "id")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
 	}
 
-	product, err := h.ProductDB.FindById(id)
+	product, err := h.ProductDB.FindById(id.String())
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
+		problem.Error(w, r, err)
+		return
 	}
-	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(product)
+
+	w.Header().Set("ETag", etag(product.Version))
+	if r.Header.Get("If-None-Match") == etag(product.Version) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	imageURLs(h.Storage, product.Images)
+	writeJSON(w, http.StatusOK, product)
 }
 
 // UpdateProduct godoc
 // @Summary      Update a product
-// @Description  Update a product
+// @Description  Update a product. If-Match must carry the ETag the product was read with.
 // @Tags         products
 // @Accept       json
 // @Produce      json
 // @Param        id        	path      string                  true  "product ID" Format(uuid)
 // @Param        request     body      dto.CreateProductInput  true  "product request"
+// @Param        If-Match    header    string                  true  "ETag of the product"
 // @Success      200
-// @Failure      404
-// @Failure      500       {object}  Error
+// @Header       200       {string}  ETag  "new version of the product"
+// @Failure      400       {object}  problem.Problem
+// @Failure      404       {object}  problem.Problem
+// @Failure      412       {object}  problem.Problem
+// @Failure      428       {object}  problem.Problem
+// @Failure      500       {object}  problem.Problem
 // @Router       /products/{id} [put]
 // @Security ApiKeyAuth
 func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
-	id := chi.URLParam(r, "id")
-	if id == "" {
-		w.WriteHeader(http.StatusBadRequest)
+	id, err := idParam(r, "id")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	var input dto.CreateProductInput
+	if err = decodeJSON(r, &input); err != nil {
+		problem.Error(w, r, err)
+		return
 	}
 
-	var product entity.Product
-	err := json.NewDecoder(r.Body).Decode(&product)
+	product, err := h.ProductDB.FindById(id.String())
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		problem.Error(w, r, err)
+		return
+	}
+	if err = checkIfMatch(r, product.Version); err != nil {
+		problem.Error(w, r, err)
+		return
 	}
 
-	_, err = h.ProductDB.FindById(id)
+	price, err := parsePrice(input.Price, input.Currency)
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
+		problem.Error(w, r, err)
+		return
 	}
 
-	product.ID, err = entityPkg.ParseID(id)
+	product.Name = input.Name
+	product.Price = price
+	if err = product.Validate(); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	if err = h.ProductDB.WithContext(r.Context()).Update(product); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(product.Version))
+	w.WriteHeader(http.StatusOK)
+}
+
+// PatchProduct godoc
+// @Summary      Partially update a product
+// @Description  Apply a JSON Merge Patch (RFC 7396, application/merge-patch+json) or a JSON Patch (RFC 6902, application/json-patch+json) to the name and price of a product. The price amount is in minor units. If-Match must carry the ETag the product was read with.
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        id        path      string                true  "product ID" Format(uuid)
+// @Param        request   body      dto.ProductDocument   true  "patch, any subset of the document"
+// @Param        If-Match  header    string                true  "ETag of the product"
+// @Success      200       {object}  entity.Product
+// @Header       200       {string}  ETag  "new version of the product"
+// @Failure      400       {object}  problem.Problem
+// @Failure      404       {object}  problem.Problem
+// @Failure      409       {object}  problem.Problem
+// @Failure      412       {object}  problem.Problem
+// @Failure      415       {object}  problem.Problem
+// @Failure      428       {object}  problem.Problem
+// @Failure      500       {object}  problem.Problem
+// @Router       /products/{id} [patch]
+// @Security ApiKeyAuth
+func (h *ProductHandler) PatchProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := idParam(r, "id")
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		problem.Error(w, r, err)
+		return
 	}
 
-	err = h.ProductDB.Update(&product)
+	apply, err := patchFunc(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		w.Header().Set("Accept-Patch", acceptPatch)
+		problem.Error(w, r, err)
+		return
 	}
-	w.WriteHeader(http.StatusOK)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		problem.Error(w, r, fmt.Errorf("%w: %v", problem.ErrInvalidBody, err))
+		return
+	}
+
+	product, err := h.ProductDB.FindById(id.String())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+	if err = checkIfMatch(r, product.Version); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	doc, err := json.Marshal(dto.ProductDocument{Name: product.Name, Price: product.Price})
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+	if doc, err = apply(doc, body); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	// fields outside the document, such as the quantity, cannot be patched
+	var patched dto.ProductDocument
+	decoder := json.NewDecoder(bytes.NewReader(doc))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&patched); err != nil {
+		problem.Error(w, r, fmt.Errorf("%w: %v", problem.ErrInvalidBody, err))
+		return
+	}
+
+	product.Name = patched.Name
+	product.Price = patched.Price
+	if err = product.Validate(); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	if err = h.ProductDB.WithContext(r.Context()).Update(product); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(product.Version))
+	imageURLs(h.Storage, product.Images)
+	writeJSON(w, http.StatusOK, product)
+}
+
+const acceptPatch = "application/merge-patch+json, application/json-patch+json"
+
+// patchFunc picks how to apply the body of a PATCH request from its content
+// type.
+func patchFunc(r *http.Request) (func(doc, body []byte) ([]byte, error), error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/merge-patch+json":
+		return patch.Merge, nil
+	case "application/json-patch+json":
+		return patch.Apply, nil
+	}
+	return nil, fmt.Errorf("%w: PATCH takes %s", problem.ErrUnsupportedMediaType, acceptPatch)
 }
 
 // DeleteProduct godoc
 // @Summary      Delete a product
-// @Description  Delete a product
+// @Description  Move a product to the trash, from where it can be restored until it is purged
 // @Tags         products
 // @Accept       json
 // @Produce      json
 // @Param        id        path      string                  true  "product ID" Format(uuid)
+// @Param        If-Match  header    string                  true  "ETag of the product"
 // @Success      200
-// @Failure      404
-// @Failure      500       {object}  Error
+// @Failure      400       {object}  problem.Problem
+// @Failure      404       {object}  problem.Problem
+// @Failure      412       {object}  problem.Problem
+// @Failure      428       {object}  problem.Problem
+// @Failure      500       {object}  problem.Problem
 // @Router       /products/{id} [delete]
 // @Security ApiKeyAuth
 func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
-	id := chi.URLParam(r, "id")
-	if id == "" {
-		w.WriteHeader(http.StatusBadRequest)
+	id, err := idParam(r, "id")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
 	}
 
-	_, err := h.ProductDB.FindById(id)
+	product, err := h.ProductDB.FindById(id.String())
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
+		problem.Error(w, r, err)
+		return
+	}
+	if err = checkIfMatch(r, product.Version); err != nil {
+		problem.Error(w, r, err)
+		return
 	}
 
-	err = h.ProductDB.Delete(id)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+	if err = h.ProductDB.WithContext(r.Context()).Delete(id.String(), product.Version); err != nil {
+		problem.Error(w, r, err)
+		return
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -159,46 +331,195 @@ func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 
 // GetProducts godoc
 // @Summary      List products
-// @Description  get all products
+// @Description  List products, filtered and sorted. Unknown parameters and sort fields are rejected. Listings sorted by created_at also return a next_cursor to continue from.
 // @Tags         products
 // @Accept       json
 // @Produce      json
-// @Param        page      query     string  false  "page number"
-// @Param        limit     query     string  false  "limit"
-// @Success      200       {array}   entity.Product
-// @Failure      404       {object}  Error
-// @Failure      500       {object}  Error
+// @Param        page          query     string  false  "page number"
+// @Param        limit         query     string  false  "page size, capped by the configured maximum"
+// @Param        sort          query     string  false  "comma separated fields among name, price, quantity and created_at, descending when prefixed with -"
+// @Param        category      query     string  false  "category ID, includes its subcategories" Format(uuid)
+// @Param        name          query     string  false  "name contains, case insensitive"
+// @Param        price_min     query     string  false  "minimum price, as a decimal"
+// @Param        price_max     query     string  false  "maximum price, as a decimal"
+// @Param        currency      query     string  false  "price currency, USD when a price bound is given without it"
+// @Param        created_from  query     string  false  "created at or after, date or RFC 3339 timestamp"
+// @Param        created_to    query     string  false  "created at or before, date or RFC 3339 timestamp"
+// @Param        cursor        query     string  false  "next_cursor of the previous response, to page by cursor instead of page number"
+// @Success      200       {object}  database.ProductResponse
+// @Failure      400       {object}  problem.Problem
+// @Failure      500       {object}  problem.Problem
 // @Router       /products [get]
 // @Security ApiKeyAuth
 func (h *ProductHandler) GetProducts(w http.ResponseWriter, r *http.Request) {
-	page := r.URL.Query().Get("page")
-	limit := r.URL.Query().Get("limit")
-	sort := r.URL.Query().Get("sort")
+	query, err := database.ParseProductQuery(r.URL.Query())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+	query.Page, query.Limit = pageBounds(r, query.Page, query.Limit)
+	if err = applyCursor(r, &query); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
 
-	pageInt, err := strconv.Atoi(page)
+	if err = h.categoryFilter(&query); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	productsList, err := h.ProductDB.FindByQuery(query)
 	if err != nil {
-		pageInt = 0
+		problem.Error(w, r, err)
+		return
+	}
+	if err = setCursor(w, r, query, &productsList); err != nil {
+		problem.Error(w, r, err)
+		return
 	}
+	setPageLinks(w, r, &productsList)
 
-	limitInt, err := strconv.Atoi(limit)
+	writeJSON(w, http.StatusOK, productsList)
+}
+
+// categoryFilter widens the category filter of query to the descendants of
+// the category.
+func (h *ProductHandler) categoryFilter(query *database.ProductQuery) error {
+	if query.CategoryID == "" {
+		return nil
+	}
+
+	var err error
+	query.CategoryIDs, err = h.CategoryDB.Descendants(query.CategoryID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return entity.ErrCategoryNotFound
+	}
+	return err
+}
+
+// SetProductCategories godoc
+// @Summary      Assign product categories
+// @Description  Replace the categories a product is assigned to
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        id          path      string                         true  "product ID" Format(uuid)
+// @Param        request     body      dto.SetProductCategoriesInput  true  "category IDs"
+// @Success      200
+// @Failure      400         {object}  problem.Problem
+// @Failure      404         {object}  problem.Problem
+// @Failure      500         {object}  problem.Problem
+// @Router       /products/{id}/categories [put]
+// @Security ApiKeyAuth
+func (h *ProductHandler) SetProductCategories(w http.ResponseWriter, r *http.Request) {
+	id, err := idParam(r, "id")
 	if err != nil {
-		pageInt = 0
+		problem.Error(w, r, err)
+		return
 	}
 
-	if pageInt < 0 {
-		pageInt = 0
+	var input dto.SetProductCategoriesInput
+	if err = decodeJSON(r, &input); err != nil {
+		problem.Error(w, r, err)
+		return
 	}
 
-	if sort == "" {
-		sort = "asc"
+	for _, categoryID := range input.CategoryIDs {
+		if _, err = entityPkg.ParseID(categoryID); err != nil {
+			problem.Error(w, r, entity.ErrInvalidId)
+			return
+		}
 	}
 
-	productsList, err := h.ProductDB.FindAll(pageInt, limitInt, sort)
+	if err = h.ProductDB.WithContext(r.Context()).SetCategories(id.String(), input.CategoryIDs); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// SearchProducts godoc
+// @Summary      Search products
+// @Description  Full-text search over the product names, most relevant first. Every word must match, as a prefix.
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        q         query     string  true   "search words"
+// @Param        page      query     string  false  "page number"
+// @Param        limit     query     string  false  "page size, capped by the configured maximum"
+// @Success      200       {object}  database.ProductResponse
+// @Failure      400       {object}  problem.Problem
+// @Failure      500       {object}  problem.Problem
+// @Router       /products/search [get]
+// @Security ApiKeyAuth
+func (h *ProductHandler) SearchProducts(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		problem.Write(w, r, http.StatusBadRequest, "query_required", "the q parameter is required")
+		return
+	}
+
+	page, limit := pagination(r)
+	products, err := h.ProductDB.Search(q, page, limit)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+	setPageLinks(w, r, &products)
+
+	writeJSON(w, http.StatusOK, products)
+}
+
+// GetTrash godoc
+// @Summary      List deleted products
+// @Description  List the products in the trash, the most recently deleted first
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        page      query     string  false  "page number"
+// @Param        limit     query     string  false  "page size, capped by the configured maximum"
+// @Success      200       {object}  database.ProductResponse
+// @Failure      403       {object}  problem.Problem
+// @Failure      500       {object}  problem.Problem
+// @Router       /products/trash [get]
+// @Security ApiKeyAuth
+func (h *ProductHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	page, limit := pagination(r)
+	products, err := h.ProductDB.FindDeleted(page, limit)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	setPageLinks(w, r, &products)
+	writeJSON(w, http.StatusOK, products)
+}
+
+// RestoreProduct godoc
+// @Summary      Restore a product
+// @Description  Take a deleted product back out of the trash
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        id        path      string  true  "product ID" Format(uuid)
+// @Success      200
+// @Failure      400       {object}  problem.Problem
+// @Failure      404       {object}  problem.Problem
+// @Failure      500       {object}  problem.Problem
+// @Router       /products/{id}/restore [post]
+// @Security ApiKeyAuth
+func (h *ProductHandler) RestoreProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := idParam(r, "id")
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		problem.Error(w, r, err)
+		return
+	}
+
+	if err = h.ProductDB.WithContext(r.Context()).Restore(id.String()); err != nil {
+		problem.Error(w, r, err)
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(productsList)
 }
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/sallescosta/user-and-products-manager/internal/dto"
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/database"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/webserver/problem"
+)
+
+type StockHandler struct {
+	StockDB database.StockInterface
+}
+
+func NewStockHandler(db database.StockInterface) *StockHandler {
+	return &StockHandler{
+		StockDB: db,
+	}
+}
+
+// CreateStockMovement godoc
+// @Summary      Post a stock movement
+// @Description  Record a receipt, sale or adjustment of a product. Movements that would make the stock negative are refused unless allow_negative is set.
+// @Tags         stock
+// @Accept       json
+// @Produce      json
+// @Param        id          path      string                        true  "product ID" Format(uuid)
+// @Param        request     body      dto.CreateStockMovementInput  true  "stock movement"
+// @Success      201         {object}  entity.StockMovement
+// @Failure      400         {object}  problem.Problem
+// @Failure      404         {object}  problem.Problem
+// @Failure      409         {object}  problem.Problem
+// @Failure      500         {object}  problem.Problem
+// @Router       /products/{id}/stock/movements [post]
+// @Security ApiKeyAuth
+func (h *StockHandler) CreateStockMovement(w http.ResponseWriter, r *http.Request) {
+	productID, err := idParam(r, "id")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	userID, err := subject(r)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	var input dto.CreateStockMovementInput
+	if err = decodeJSON(r, &input); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	movement, err := entity.NewStockMovement(productID, userID, entity.MovementType(input.Type), input.Quantity, input.Reason)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	if err = h.StockDB.WithContext(r.Context()).Record(movement, input.AllowNegative); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, movement)
+}
+
+// GetStock godoc
+// @Summary      Get product stock
+// @Description  Get the on-hand quantity of a product
+// @Tags         stock
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string   true "product ID" Format(uuid)
+// @Success      200  {object}  dto.GetStockOutput
+// @Failure      400  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /products/{id}/stock [get]
+// @Security ApiKeyAuth
+func (h *StockHandler) GetStock(w http.ResponseWriter, r *http.Request) {
+	productID, err := idParam(r, "id")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	quantity, err := h.StockDB.OnHand(productID.String())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, dto.GetStockOutput{ProductID: productID.String(), Quantity: quantity})
+}
+
+// GetStockMovements godoc
+// @Summary      List stock movements
+// @Description  List the stock ledger of a product, newest first, without the movements of its variants
+// @Tags         stock
+// @Accept       json
+// @Produce      json
+// @Param        id        path      string  true   "product ID" Format(uuid)
+// @Param        page      query     string  false  "page number"
+// @Param        limit     query     string  false  "limit"
+// @Success      200       {array}   entity.StockMovement
+// @Failure      400       {object}  problem.Problem
+// @Failure      404       {object}  problem.Problem
+// @Failure      500       {object}  problem.Problem
+// @Router       /products/{id}/stock/movements [get]
+// @Security ApiKeyAuth
+func (h *StockHandler) GetStockMovements(w http.ResponseWriter, r *http.Request) {
+	productID, err := idParam(r, "id")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	page, limit := pagination(r)
+	movements, err := h.StockDB.Movements(productID.String(), page, limit)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, movements)
+}
+
+// GetVariantStockMovements godoc
+// @Summary      List the stock movements of a variant
+// @Description  List the stock ledger of a variant, newest first. Its quantity is changed through the variant, and each change is recorded as an adjustment.
+// @Tags         stock
+// @Accept       json
+// @Produce      json
+// @Param        id         path      string  true   "product ID" Format(uuid)
+// @Param        variantId  path      string  true   "variant ID" Format(uuid)
+// @Param        page       query     string  false  "page number"
+// @Param        limit      query     string  false  "limit"
+// @Success      200        {array}   entity.StockMovement
+// @Failure      400        {object}  problem.Problem
+// @Failure      404        {object}  problem.Problem
+// @Failure      500        {object}  problem.Problem
+// @Router       /products/{id}/variants/{variantId}/stock/movements [get]
+// @Security ApiKeyAuth
+func (h *StockHandler) GetVariantStockMovements(w http.ResponseWriter, r *http.Request) {
+	productID, err := idParam(r, "id")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	variantID, err := idParam(r, "variantId")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	page, limit := pagination(r)
+	movements, err := h.StockDB.VariantMovements(productID.String(), variantID.String(), page, limit)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, movements)
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/sallescosta/user-and-products-manager/internal/dto"
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/webserver/problem"
+	entityPkg "github.com/sallescosta/user-and-products-manager/pkg/entity"
+)
+
+// RefreshToken godoc
+// @Summary      Refresh a user JWT
+// @Description  Exchange a refresh token for a new access token. The refresh token is rotated: the one sent is revoked and a new one is returned.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request   body     dto.RefreshTokenInput  true  "refresh token"
+// @Success      200  {object}  dto.GetJWTOutput
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /users/refresh_token [post]
+func (h *UserHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var input dto.RefreshTokenInput
+	if err := decodeJSON(r, &input); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+	if input.RefreshToken == "" {
+		problem.Error(w, r, entity.ErrInvalidRefreshToken)
+		return
+	}
+
+	token, err := h.RefreshTokenDB.FindByHash(entity.HashToken(input.RefreshToken))
+	if err != nil {
+		problem.Error(w, r, entity.ErrInvalidRefreshToken)
+		return
+	}
+
+	if err = token.Validate(time.Now()); err != nil {
+		// a revoked token being presented again means it leaked: whoever holds
+		// the newer tokens of that family is not to be trusted either
+		if errors.Is(err, entity.ErrRefreshTokenRevoked) {
+			_ = h.RefreshTokenDB.RevokeAllForUser(token.UserID.String())
+		}
+		problem.Error(w, r, err)
+		return
+	}
+
+	u, err := h.UserDB.FindById(token.UserID.String())
+	if err != nil {
+		problem.Error(w, r, entity.ErrInvalidRefreshToken)
+		return
+	}
+
+	h.issueTokens(w, r, u, token)
+}
+
+// Logout godoc
+// @Summary      Logout
+// @Description  Revoke the access token used on the request and, when given, the refresh token
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request   body     dto.RefreshTokenInput  false  "refresh token"
+// @Success      204
+// @Failure      401  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /users/logout [post]
+// @Security ApiKeyAuth
+func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	accessToken, claims, err := jwtauth.FromContext(r.Context())
+	if err != nil || accessToken == nil {
+		problem.Write(w, r, http.StatusUnauthorized, "unauthorized", "a valid access token is required")
+		return
+	}
+
+	// the body is optional: without it only the access token is revoked
+	var input dto.RefreshTokenInput
+	_ = decodeJSON(r, &input)
+
+	if input.RefreshToken != "" {
+		token, err := h.RefreshTokenDB.FindByHash(entity.HashToken(input.RefreshToken))
+		if err == nil && token.UserID.String() == claims["sub"] {
+			if err = h.RefreshTokenDB.Revoke(token.ID.String()); err != nil {
+				problem.Error(w, r, err)
+				return
+			}
+		}
+	}
+
+	if jti := accessToken.JwtID(); jti != "" {
+		if err = h.RevokedTokenDB.Revoke(jti, accessToken.Expiration()); err != nil {
+			problem.Error(w, r, err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// issueTokens writes a new access token and refresh token pair for the user,
+// unless their email has to be verified first. When rotated is given, that
+// refresh token is revoked in the same step.
+func (h *UserHandler) issueTokens(w http.ResponseWriter, r *http.Request, u *entity.User, rotated ...*entity.RefreshToken) {
+	if h.Verification.Required && !u.Verified {
+		problem.Error(w, r, entity.ErrEmailNotVerified)
+		return
+	}
+
+	jwt := r.Context().Value("jwt").(*jwtauth.JWTAuth)
+	jwtExpiresIn := r.Context().Value("JwtExpiresIn").(int)
+	jwtRefreshExpiresIn := r.Context().Value("JwtRefreshExpiresIn").(int)
+
+	refreshToken, refreshTokenString, err := entity.NewRefreshToken(u.ID, time.Second*time.Duration(jwtRefreshExpiresIn))
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	if len(rotated) > 0 {
+		err = h.RefreshTokenDB.Rotate(rotated[0], refreshToken)
+	} else {
+		err = h.RefreshTokenDB.Create(refreshToken)
+	}
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	m := map[string]interface{}{
+		"sub":  u.ID.String(),
+		"role": string(u.Role),
+		"jti":  entityPkg.NewID().String(),
+		"exp":  time.Now().Add(time.Second * time.Duration(jwtExpiresIn)).Unix(),
+	}
+	_, tokenString, err := jwt.Encode(m)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, dto.GetJWTOutput{
+		AccessToken:  tokenString,
+		RefreshToken: refreshTokenString,
+	})
+}
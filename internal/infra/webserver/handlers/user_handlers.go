@@ -1,7 +1,9 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"time"
 
@@ -9,118 +11,311 @@ import (
 	"github.com/sallescosta/user-and-products-manager/internal/dto"
 	"github.com/sallescosta/user-and-products-manager/internal/entity"
 	"github.com/sallescosta/user-and-products-manager/internal/infra/database"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/mail"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/webserver/problem"
+	"gorm.io/gorm"
 )
 
-type Error struct {
-	Message string `json:"message"`
+type UserHandler struct {
+	UserDB         database.UserInterface
+	RefreshTokenDB database.RefreshTokenInterface
+	RevokedTokenDB database.RevokedTokenInterface
+	Verification   Verification
+	PasswordPolicy *entity.PasswordPolicy
+	LoginThrottle  LoginThrottle
+	Jwt            *jwtauth.JWTAuth
+	JwtExpiresIn   int
 }
 
-type UserHandler struct {
-	UserDB       database.UserInterface
-	Jwt          *jwtauth.JWTAuth
-	JwtExpiresIn int
+// Verification is how the emails of new users are verified.
+type Verification struct {
+	Mailer mail.Mailer
+	// URL is where the emailed links point to: GET /users/verify, or a page
+	// of the client calling it. The token is added as the "token" query
+	// parameter.
+	URL       string
+	ExpiresIn time.Duration
+	// Required refuses tokens to the users whose email is not verified.
+	Required bool
 }
 
-func NewUserHandler(userDB database.UserInterface) *UserHandler {
-	return &UserHandler{UserDB: userDB}
+func NewUserHandler(userDB database.UserInterface, refreshTokenDB database.RefreshTokenInterface, revokedTokenDB database.RevokedTokenInterface, verification Verification, passwordPolicy *entity.PasswordPolicy, loginThrottle LoginThrottle) *UserHandler {
+	return &UserHandler{
+		UserDB:         userDB,
+		RefreshTokenDB: refreshTokenDB,
+		RevokedTokenDB: revokedTokenDB,
+		Verification:   verification,
+		PasswordPolicy: passwordPolicy,
+		LoginThrottle:  loginThrottle,
+	}
 }
 
 // GetJWT godoc
 // @Summary      Get a user JWT
-// @Description  Get a user JWT
+// @Description  Get a user JWT together with a refresh token. Failed logins block the account and the IP address for a while, longer after each failure, and lock them out after too many.
 // @Tags         users
 // @Accept       json
 // @Produce      json
 // @Param        request   body     dto.GetJWTInput  true  "user credentials"
 // @Success      200  {object}  dto.GetJWTOutput
-// @Failure      404  {object}  Error
-// @Failure      500  {object}  Error
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      429  {object}  problem.Problem
+// @Header       429  {integer}  Retry-After  "seconds to wait before the next login"
+// @Failure      500  {object}  problem.Problem
 // @Router       /users/generate_token [post]
 func (h *UserHandler) GetJWT(w http.ResponseWriter, r *http.Request) {
-	jwt := r.Context().Value("jwt").(*jwtauth.JWTAuth)
-	jwtExpiresIn := r.Context().Value("JwtExpiresIn").(int)
-
 	var user dto.GetJWTInput
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+	if err := decodeJSON(r, &user); err != nil {
+		problem.Error(w, r, err)
 		return
 	}
 
-	u, err := h.UserDB.FindByEmail(user.Email)
-	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
-		err := Error{Message: err.Error()}
-		json.NewEncoder(w).Encode(err)
-	}
-	if !u.ValidatePassword(user.Password) {
-		w.WriteHeader(http.StatusUnauthorized)
+	if !h.LoginThrottle.allow(w, r, user.Email) {
 		return
 	}
 
-	m := map[string]interface{}{
-		"sub": u.ID.String(),
-		"exp": time.Now().Add(time.Second * time.Duration(jwtExpiresIn)).Unix(),
+	// unknown emails fail like wrong passwords, and as slowly, so as not to
+	// tell which accounts exist
+	u, err := h.UserDB.FindByEmail(user.Email)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		problem.Error(w, r, err)
+		return
 	}
-	_, tokenString, _ := jwt.Encode(m)
-
-	accessToken := dto.GetJWTOutput{AccessToken: tokenString}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	err = json.NewEncoder(w).Encode(accessToken)
-	if err != nil {
+	valid := false
+	if u != nil {
+		valid = u.ValidatePassword(user.Password)
+	} else {
+		entity.DummyPasswordCheck(user.Password)
+	}
+	if !valid {
+		h.LoginThrottle.fail(r, user.Email)
+		problem.Error(w, r, entity.ErrInvalidCredentials)
+		return
+	}
+	if err = h.LoginThrottle.succeed(u.Email); err != nil {
+		problem.Error(w, r, err)
 		return
 	}
+
+	h.issueTokens(w, r, u)
 }
 
 // Create user godoc
 // @Summary      Create user
-// @Description  Create user
+// @Description  Create user, and email them a link to verify their email
 // @Tags         users
 // @Accept       json
 // @Produce      json
 // @Param        request     body      dto.CreateUserInput  true  "user request"
 // @Success      201
-// @Failure      500         {object}  Error
+// @Failure      400         {object}  problem.Problem
+// @Failure      409         {object}  problem.Problem
+// @Failure      500         {object}  problem.Problem
 // @Router       /users [post]
 func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	var user dto.CreateUserInput
+	if err := decodeJSON(r, &user); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
 
-	err := json.NewDecoder(r.Body).Decode(&user)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+	if err := h.PasswordPolicy.Check(user.Password); err != nil {
+		problem.Error(w, r, err)
 		return
 	}
 
 	u, err := entity.NewUser(user.Name, user.Email, user.Password)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		error := Error{Message: err.Error()}
-		json.NewEncoder(w).Encode(error)
+		problem.Error(w, r, err)
+		return
 	}
 
-	err = h.UserDB.Create(u)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		error := Error{Message: err.Error()}
-		json.NewEncoder(w).Encode(error)
+	// the very first account becomes the admin, so there is always someone
+	// able to promote the users registered afterwards
+	if err = h.UserDB.Register(u); err != nil {
+		problem.Error(w, r, err)
 		return
 	}
 
+	// the account exists anyway: a link that could not be sent is only
+	// logged
+	if err = h.sendVerification(r, u); err != nil {
+		log.Printf("sending the verification email of user %s: %v", u.ID, err)
+	}
+
 	w.WriteHeader(http.StatusCreated)
 }
 
-func (h *UserHandler) AllUsers(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) sendVerification(r *http.Request, u *entity.User) error {
+	if h.Verification.Mailer == nil {
+		return nil
+	}
+
+	token, err := signerFrom(r).Sign(entity.NewEmailVerification(u, h.Verification.ExpiresIn))
+	if err != nil {
+		return err
+	}
+	link := tokenLink(h.Verification.URL, token)
+
+	return h.Verification.Mailer.Send(r.Context(), mail.Message{
+		To:      u.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"Welcome! To verify that this email is yours, open:\n\n"+
+			"%s\n\n"+
+			"The link works for %d hours. If you did not sign up, ignore this email.\n",
+			u.Name, link, int(h.Verification.ExpiresIn.Hours())),
+	})
+}
+
+// VerifyEmail godoc
+// @Summary      Verify email
+// @Description  Mark the email of a user as verified, with the token of the link emailed on signup
+// @Tags         users
+// @Produce      json
+// @Param        token  query     string  true  "verification token"
+// @Success      200    {object}  dto.VerifyEmailOutput
+// @Failure      400    {object}  problem.Problem
+// @Failure      500    {object}  problem.Problem
+// @Router       /users/verify [get]
+func (h *UserHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		problem.Error(w, r, entity.ErrInvalidVerificationToken)
+		return
+	}
+
+	var verification entity.EmailVerification
+	if err := signerFrom(r).Verify(token, &verification); err != nil {
+		problem.Error(w, r, entity.ErrInvalidVerificationToken)
+		return
+	}
+
+	u, err := h.UserDB.FindById(verification.UserID)
+	if err != nil {
+		problem.Error(w, r, entity.ErrInvalidVerificationToken)
+		return
+	}
+	if err = verification.Validate(u, time.Now()); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
 
+	// opening the link again is fine
+	if !u.Verified {
+		u.Verified = true
+		if err = h.UserDB.Update(u); err != nil {
+			problem.Error(w, r, err)
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, dto.VerifyEmailOutput{Email: u.Email, Verified: true})
+}
+
+// AllUsers godoc
+// @Summary      List users
+// @Description  List all users (admin only)
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Success      200  {array}   entity.User
+// @Failure      401  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /users [get]
+// @Security ApiKeyAuth
+func (h *UserHandler) AllUsers(w http.ResponseWriter, r *http.Request) {
 	list, err := h.UserDB.GetAllUsers()
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		problem.Error(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, list)
+}
+
+// UpdateUserRole godoc
+// @Summary      Update user role
+// @Description  Change the role of a user (admin only)
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        id          path      string                   true  "user ID" Format(uuid)
+// @Param        request     body      dto.UpdateUserRoleInput  true  "role request"
+// @Success      200
+// @Failure      400         {object}  problem.Problem
+// @Failure      403         {object}  problem.Problem
+// @Failure      404         {object}  problem.Problem
+// @Failure      500         {object}  problem.Problem
+// @Router       /users/{id}/role [put]
+// @Security ApiKeyAuth
+func (h *UserHandler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	id, err := idParam(r, "id")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	var input dto.UpdateUserRoleInput
+	if err = decodeJSON(r, &input); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	role, err := entity.ParseRole(input.Role)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	u, err := h.UserDB.FindById(id.String())
+	if err != nil {
+		problem.Error(w, r, err)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
+
+	u.Role = role
+	if err = h.UserDB.Update(u); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
-	err = json.NewEncoder(w).Encode(list)
+}
+
+// UnlockUser godoc
+// @Summary      Unlock user
+// @Description  Clear the failed logins of a user, lifting the lockout of their account (admin only). The failures of IP addresses are kept.
+// @Tags         users
+// @Produce      json
+// @Param        id   path      string  true  "user ID" Format(uuid)
+// @Success      204
+// @Failure      400  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /users/{id}/unlock [post]
+// @Security ApiKeyAuth
+func (h *UserHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	id, err := idParam(r, "id")
 	if err != nil {
+		problem.Error(w, r, err)
 		return
 	}
+
+	u, err := h.UserDB.FindById(id.String())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	if err = h.LoginThrottle.succeed(u.Email); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/database"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// fakeUsers finds the users it holds by email, and has no other use.
+type fakeUsers struct {
+	database.UserInterface
+	users []*entity.User
+}
+
+func (f fakeUsers) FindByEmail(email string) (*entity.User, error) {
+	for _, user := range f.users {
+		if user.Email == entity.NormalizeEmail(email) {
+			return user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func TestGetJWT_UnknownEmailLikeWrongPassword(t *testing.T) {
+	john, _ := entity.NewUser("John", "john@gmail.com", "95432187")
+	handler := NewUserHandler(fakeUsers{users: []*entity.User{john}}, nil, nil, Verification{}, nil, LoginThrottle{})
+
+	login := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.GetJWT(rec, httptest.NewRequest(http.MethodPost, "/users/generate_token", strings.NewReader(body)))
+		return rec
+	}
+	wrongPassword := login(`{"email": "john@gmail.com", "password": "12345678"}`)
+	unknownEmail := login(`{"email": "jane@gmail.com", "password": "12345678"}`)
+
+	assert.Equal(t, http.StatusUnauthorized, wrongPassword.Code)
+	assert.Equal(t, wrongPassword.Code, unknownEmail.Code)
+	assert.Equal(t, wrongPassword.Header(), unknownEmail.Header())
+	assert.Equal(t, wrongPassword.Body.String(), unknownEmail.Body.String())
+	assert.Contains(t, unknownEmail.Body.String(), `"code":"invalid_credentials"`)
+}
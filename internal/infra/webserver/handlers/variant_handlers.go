@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/sallescosta/user-and-products-manager/internal/dto"
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/database"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/webserver/problem"
+	entityPkg "github.com/sallescosta/user-and-products-manager/pkg/entity"
+)
+
+type VariantHandler struct {
+	VariantDB database.VariantInterface
+}
+
+func NewVariantHandler(db database.VariantInterface) *VariantHandler {
+	return &VariantHandler{
+		VariantDB: db,
+	}
+}
+
+// variantPrice is the price override of input, nil when it has none.
+func variantPrice(input dto.VariantInput) (*entityPkg.Money, error) {
+	if input.Price == "" {
+		return nil, nil
+	}
+	price, err := parsePrice(input.Price, input.Currency)
+	if err != nil {
+		return nil, err
+	}
+	return &price, nil
+}
+
+// GetVariants godoc
+// @Summary      List the variants of a product
+// @Description  List the variants of a product, by SKU
+// @Tags         variants
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "product ID" Format(uuid)
+// @Success      200  {array}   entity.Variant
+// @Failure      400  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /products/{id}/variants [get]
+// @Security ApiKeyAuth
+func (h *VariantHandler) GetVariants(w http.ResponseWriter, r *http.Request) {
+	productID, err := idParam(r, "id")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	variants, err := h.VariantDB.FindByProduct(productID.String())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, variants)
+}
+
+// GetVariant godoc
+// @Summary      Get a variant
+// @Description  Get a variant of a product
+// @Tags         variants
+// @Accept       json
+// @Produce      json
+// @Param        id         path      string  true  "product ID" Format(uuid)
+// @Param        variantId  path      string  true  "variant ID" Format(uuid)
+// @Success      200        {object}  entity.Variant
+// @Failure      400        {object}  problem.Problem
+// @Failure      404        {object}  problem.Problem
+// @Failure      500        {object}  problem.Problem
+// @Router       /products/{id}/variants/{variantId} [get]
+// @Security ApiKeyAuth
+func (h *VariantHandler) GetVariant(w http.ResponseWriter, r *http.Request) {
+	productID, err := idParam(r, "id")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	variantID, err := idParam(r, "variantId")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	variant, err := h.VariantDB.FindById(productID.String(), variantID.String())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, variant)
+}
+
+// CreateVariant godoc
+// @Summary      Create a variant
+// @Description  Add a variant to a product. Its SKU must be unique; without a price it is sold at the price of the product.
+// @Tags         variants
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string            true  "product ID" Format(uuid)
+// @Param        request  body      dto.VariantInput  true  "variant request"
+// @Success      201      {object}  entity.Variant
+// @Failure      400      {object}  problem.Problem
+// @Failure      404      {object}  problem.Problem
+// @Failure      409      {object}  problem.Problem
+// @Failure      500      {object}  problem.Problem
+// @Router       /products/{id}/variants [post]
+// @Security ApiKeyAuth
+func (h *VariantHandler) CreateVariant(w http.ResponseWriter, r *http.Request) {
+	productID, err := idParam(r, "id")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	var input dto.VariantInput
+	if err = decodeJSON(r, &input); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	price, err := variantPrice(input)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	variant, err := entity.NewVariant(productID, input.SKU, input.Attributes, price, input.Quantity)
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	if err = h.VariantDB.WithContext(r.Context()).Create(variant); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, variant)
+}
+
+// UpdateVariant godoc
+// @Summary      Update a variant
+// @Description  Replace the SKU, attributes, price and stock of a variant. Omitting the price clears its override. A change of the stock is recorded in the stock ledger of the variant as an adjustment.
+// @Tags         variants
+// @Accept       json
+// @Produce      json
+// @Param        id         path      string            true  "product ID" Format(uuid)
+// @Param        variantId  path      string            true  "variant ID" Format(uuid)
+// @Param        request    body      dto.VariantInput  true  "variant request"
+// @Success      200        {object}  entity.Variant
+// @Failure      400        {object}  problem.Problem
+// @Failure      404        {object}  problem.Problem
+// @Failure      409        {object}  problem.Problem
+// @Failure      500        {object}  problem.Problem
+// @Router       /products/{id}/variants/{variantId} [put]
+// @Security ApiKeyAuth
+func (h *VariantHandler) UpdateVariant(w http.ResponseWriter, r *http.Request) {
+	productID, err := idParam(r, "id")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	variantID, err := idParam(r, "variantId")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	variant, err := h.VariantDB.FindById(productID.String(), variantID.String())
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	var input dto.VariantInput
+	if err = decodeJSON(r, &input); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	if variant.Price, err = variantPrice(input); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+	variant.SKU = entity.NormalizeSKU(input.SKU)
+	variant.Attributes = input.Attributes
+	if variant.Attributes == nil {
+		variant.Attributes = entity.VariantAttributes{}
+	}
+	variant.Quantity = input.Quantity
+
+	if err = variant.Validate(); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	if err = h.VariantDB.WithContext(r.Context()).Update(variant); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, variant)
+}
+
+// DeleteVariant godoc
+// @Summary      Delete a variant
+// @Description  Delete a variant of a product
+// @Tags         variants
+// @Accept       json
+// @Produce      json
+// @Param        id         path      string  true  "product ID" Format(uuid)
+// @Param        variantId  path      string  true  "variant ID" Format(uuid)
+// @Success      200
+// @Failure      400        {object}  problem.Problem
+// @Failure      404        {object}  problem.Problem
+// @Failure      500        {object}  problem.Problem
+// @Router       /products/{id}/variants/{variantId} [delete]
+// @Security ApiKeyAuth
+func (h *VariantHandler) DeleteVariant(w http.ResponseWriter, r *http.Request) {
+	productID, err := idParam(r, "id")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	variantID, err := idParam(r, "variantId")
+	if err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	if err = h.VariantDB.WithContext(r.Context()).Delete(productID.String(), variantID.String()); err != nil {
+		problem.Error(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
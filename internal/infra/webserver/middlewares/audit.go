@@ -0,0 +1,23 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/jwtauth"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/database"
+)
+
+// AuditActor attributes the writes of the request, in the audit log, to the
+// user in the "sub" claim of its JWT and to its request ID. It must run after
+// middleware.RequestID, jwtauth.Verifier and Authenticator.
+func AuditActor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor := database.AuditActor{RequestID: middleware.GetReqID(r.Context())}
+		if _, claims, err := jwtauth.FromContext(r.Context()); err == nil {
+			actor.UserID, _ = claims["sub"].(string)
+		}
+
+		next.ServeHTTP(w, r.WithContext(database.WithAuditActor(r.Context(), actor)))
+	})
+}
@@ -0,0 +1,31 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/jwtauth"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditActor(t *testing.T) {
+	tokenAuth := jwtauth.New("HS256", []byte("secret"), nil)
+	_, tokenString, err := tokenAuth.Encode(map[string]interface{}{"sub": "user-1", "role": "editor"})
+	assert.NoError(t, err)
+
+	var actor database.AuditActor
+	record := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor = database.AuditActorFrom(r.Context())
+	})
+	handler := middleware.RequestID(jwtauth.Verifier(tokenAuth)(Authenticator(AuditActor(record))))
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenString)
+	req.Header.Set(middleware.RequestIDHeader, "req-42")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, database.AuditActor{UserID: "user-1", RequestID: "req-42"}, actor)
+}
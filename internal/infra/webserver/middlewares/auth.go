@@ -0,0 +1,83 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/database"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/webserver/problem"
+)
+
+// Authenticator works like jwtauth.Authenticator, rejecting requests without a
+// valid token verified by jwtauth.Verifier, but answers with a problem document.
+func Authenticator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, _, err := jwtauth.FromContext(r.Context())
+		if err != nil {
+			problem.Write(w, r, http.StatusUnauthorized, "unauthorized", jwtauth.ErrorReason(err).Error())
+			return
+		}
+
+		if token == nil || jwt.Validate(token) != nil {
+			problem.Write(w, r, http.StatusUnauthorized, "unauthorized", "a valid access token is required")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireRole only lets the request through when the "role" claim of the
+// verified JWT grants at least the given role. It must run after
+// jwtauth.Verifier and Authenticator.
+func RequireRole(role entity.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, claims, err := jwtauth.FromContext(r.Context())
+			if err != nil {
+				problem.Write(w, r, http.StatusUnauthorized, "unauthorized", "a valid access token is required")
+				return
+			}
+
+			claim, _ := claims["role"].(string)
+			userRole, err := entity.ParseRole(claim)
+			if err != nil || !userRole.Includes(role) {
+				problem.Write(w, r, http.StatusForbidden, "forbidden", "this operation requires the "+string(role)+" role")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RejectRevoked refuses access tokens whose "jti" claim is in the revocation
+// list, i.e. tokens of users that already logged out. It must run after
+// jwtauth.Verifier and Authenticator.
+func RejectRevoked(revokedDB database.RevokedTokenInterface) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, _, err := jwtauth.FromContext(r.Context())
+			if err != nil || token == nil {
+				problem.Write(w, r, http.StatusUnauthorized, "unauthorized", "a valid access token is required")
+				return
+			}
+
+			if jti := token.JwtID(); jti != "" {
+				revoked, err := revokedDB.IsRevoked(jti)
+				if err != nil {
+					problem.Error(w, r, err)
+					return
+				}
+				if revoked {
+					problem.Write(w, r, http.StatusUnauthorized, "token_revoked", "the access token was revoked")
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
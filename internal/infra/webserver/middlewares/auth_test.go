@@ -0,0 +1,90 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/webserver/problem"
+	"github.com/stretchr/testify/assert"
+)
+
+func serveWithRole(t *testing.T, required entity.Role, claims map[string]interface{}) int {
+	tokenAuth := jwtauth.New("HS256", []byte("secret"), nil)
+	_, tokenString, err := tokenAuth.Encode(claims)
+	assert.NoError(t, err)
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := jwtauth.Verifier(tokenAuth)(Authenticator(RequireRole(required)(ok)))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenString)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec.Code
+}
+
+func TestRequireRole(t *testing.T) {
+	assert.Equal(t, http.StatusOK, serveWithRole(t, entity.RoleEditor, map[string]interface{}{"sub": "1", "role": "editor"}))
+	assert.Equal(t, http.StatusOK, serveWithRole(t, entity.RoleEditor, map[string]interface{}{"sub": "1", "role": "admin"}))
+	assert.Equal(t, http.StatusForbidden, serveWithRole(t, entity.RoleEditor, map[string]interface{}{"sub": "1", "role": "viewer"}))
+	assert.Equal(t, http.StatusForbidden, serveWithRole(t, entity.RoleAdmin, map[string]interface{}{"sub": "1"}))
+}
+
+type revokedList map[string]bool
+
+func (l revokedList) Revoke(jti string, expiresAt time.Time) error {
+	l[jti] = true
+	return nil
+}
+
+func (l revokedList) IsRevoked(jti string) (bool, error) {
+	return l[jti], nil
+}
+
+func TestRejectRevoked(t *testing.T) {
+	tokenAuth := jwtauth.New("HS256", []byte("secret"), nil)
+	revoked := revokedList{}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := jwtauth.Verifier(tokenAuth)(Authenticator(RejectRevoked(revoked)(ok)))
+
+	serve := func(jti string) int {
+		_, tokenString, err := tokenAuth.Encode(map[string]interface{}{"sub": "1", "jti": jti})
+		assert.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+tokenString)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("a"))
+
+	_ = revoked.Revoke("a", time.Now().Add(time.Hour))
+	assert.Equal(t, http.StatusUnauthorized, serve("a"))
+	assert.Equal(t, http.StatusOK, serve("b"))
+}
+
+func TestAuthenticator_MissingToken(t *testing.T) {
+	tokenAuth := jwtauth.New("HS256", []byte("secret"), nil)
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := jwtauth.Verifier(tokenAuth)(Authenticator(ok))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, problem.ContentType, rec.Header().Get("Content-Type"))
+}
@@ -0,0 +1,90 @@
+package problem
+
+import (
+	"net/http"
+
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	"github.com/sallescosta/user-and-products-manager/internal/infra/database"
+	entityPkg "github.com/sallescosta/user-and-products-manager/pkg/entity"
+	"github.com/sallescosta/user-and-products-manager/pkg/patch"
+	"gorm.io/gorm"
+)
+
+type mapping struct {
+	err    error
+	status int
+	code   string
+}
+
+// mappings lists the errors with a known status and code. The codes are part
+// of the API contract: never change an existing one.
+var mappings = []mapping{
+	{ErrInvalidBody, http.StatusBadRequest, "invalid_body"},
+	{ErrPreconditionRequired, http.StatusPreconditionRequired, "precondition_required"},
+	{ErrUnsupportedMediaType, http.StatusUnsupportedMediaType, "unsupported_media_type"},
+	{ErrImportTooLarge, http.StatusRequestEntityTooLarge, "import_too_large"},
+	{patch.ErrInvalidPatch, http.StatusBadRequest, "invalid_patch"},
+	{patch.ErrTestFailed, http.StatusConflict, "patch_test_failed"},
+	{gorm.ErrRecordNotFound, http.StatusNotFound, "not_found"},
+	{database.ErrUnknownQueryParameter, http.StatusBadRequest, "unknown_parameter"},
+	{database.ErrUnknownSortField, http.StatusBadRequest, "unknown_sort_field"},
+	{database.ErrInvalidFilter, http.StatusBadRequest, "invalid_filter"},
+	{database.ErrInvalidCursor, http.StatusBadRequest, "invalid_cursor"},
+
+	{entity.ErrIDIsRequired, http.StatusBadRequest, "id_required"},
+	{entity.ErrInvalidId, http.StatusBadRequest, "invalid_id"},
+	{entity.ErrNameIsRequired, http.StatusBadRequest, "name_required"},
+	{entity.ErrPriceIsRequired, http.StatusBadRequest, "price_required"},
+	{entity.ErrInvalidPrice, http.StatusBadRequest, "invalid_price"},
+	{entity.ErrVersionMismatch, http.StatusPreconditionFailed, "version_mismatch"},
+	{entity.ErrEffectiveFromInPast, http.StatusBadRequest, "effective_from_in_past"},
+	{entity.ErrPriceAlreadyScheduled, http.StatusConflict, "price_already_scheduled"},
+	{entity.ErrPriceNotScheduled, http.StatusConflict, "price_not_scheduled"},
+
+	{entity.ErrSKUIsRequired, http.StatusBadRequest, "sku_required"},
+	{entity.ErrInvalidSKU, http.StatusBadRequest, "invalid_sku"},
+	{entity.ErrSKUAlreadyExists, http.StatusConflict, "sku_already_exists"},
+	{entity.ErrInvalidAttribute, http.StatusBadRequest, "invalid_attribute"},
+
+	{entity.ErrImageIsRequired, http.StatusBadRequest, "image_required"},
+	{entity.ErrUnsupportedImageType, http.StatusUnsupportedMediaType, "unsupported_image_type"},
+	{entity.ErrImageTooLarge, http.StatusRequestEntityTooLarge, "image_too_large"},
+	{entity.ErrInvalidImage, http.StatusBadRequest, "invalid_image"},
+	{entity.ErrInvalidImageOrder, http.StatusBadRequest, "invalid_image_order"},
+
+	{entityPkg.ErrInvalidCurrency, http.StatusBadRequest, "invalid_currency"},
+	{entityPkg.ErrInvalidAmount, http.StatusBadRequest, "invalid_amount"},
+	{entityPkg.ErrInvalidPrecision, http.StatusBadRequest, "invalid_precision"},
+	{entityPkg.ErrCurrencyMismatch, http.StatusBadRequest, "currency_mismatch"},
+	{entityPkg.ErrAmountOverflow, http.StatusBadRequest, "amount_out_of_range"},
+
+	{entity.ErrCategoryCycle, http.StatusBadRequest, "category_cycle"},
+	{entity.ErrCategoryHasChildren, http.StatusConflict, "category_has_children"},
+	{entity.ErrParentCategoryNotFound, http.StatusBadRequest, "parent_category_not_found"},
+	{entity.ErrCategoryNotFound, http.StatusBadRequest, "category_not_found"},
+
+	{entity.ErrInvalidMovementType, http.StatusBadRequest, "invalid_movement_type"},
+	{entity.ErrInvalidQuantity, http.StatusBadRequest, "invalid_quantity"},
+	{entity.ErrReasonIsRequired, http.StatusBadRequest, "reason_required"},
+	{entity.ErrInsufficientStock, http.StatusConflict, "insufficient_stock"},
+
+	{entity.ErrInvalidRole, http.StatusBadRequest, "invalid_role"},
+	{entity.ErrInvalidCredentials, http.StatusUnauthorized, "invalid_credentials"},
+	{entity.ErrInvalidRefreshToken, http.StatusUnauthorized, "invalid_refresh_token"},
+	{entity.ErrRefreshTokenExpired, http.StatusUnauthorized, "refresh_token_expired"},
+	{entity.ErrRefreshTokenRevoked, http.StatusUnauthorized, "refresh_token_revoked"},
+	{entity.ErrEmailNotVerified, http.StatusForbidden, "email_not_verified"},
+	{entity.ErrTooManyLoginAttempts, http.StatusTooManyRequests, "too_many_login_attempts"},
+	{entity.ErrInvalidVerificationToken, http.StatusBadRequest, "invalid_verification_token"},
+	{entity.ErrVerificationTokenExpired, http.StatusBadRequest, "verification_token_expired"},
+	{entity.ErrPasswordIsRequired, http.StatusBadRequest, "password_required"},
+	{entity.ErrPasswordTooLong, http.StatusBadRequest, "password_too_long"},
+	{entity.ErrPasswordTooShort, http.StatusBadRequest, "password_too_short"},
+	{entity.ErrPasswordBreached, http.StatusBadRequest, "password_breached"},
+	{entity.ErrEmailIsRequired, http.StatusBadRequest, "email_required"},
+	{entity.ErrInvalidEmail, http.StatusBadRequest, "invalid_email"},
+	{entity.ErrEmailAlreadyExists, http.StatusConflict, "email_already_exists"},
+	{entity.ErrInvalidResetToken, http.StatusBadRequest, "invalid_reset_token"},
+	{entity.ErrResetTokenExpired, http.StatusBadRequest, "reset_token_expired"},
+	{entity.ErrResetTokenUsed, http.StatusBadRequest, "reset_token_used"},
+}
@@ -0,0 +1,84 @@
+// Package problem writes every error response of the API as an RFC 7807
+// problem details document (application/problem+json), with a stable "code"
+// member clients can switch on instead of parsing the message.
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+)
+
+const ContentType = "application/problem+json"
+
+var (
+	ErrInvalidBody          = errors.New("invalid request body")
+	ErrPreconditionRequired = errors.New("the If-Match header is required")
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+	ErrImportTooLarge       = errors.New("the import is too large")
+)
+
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"`
+}
+
+// Write sends a problem with the given status and code.
+func Write(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	p := Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+		Code:     code,
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// Lookup returns the status and code of err from the known mappings. Errors
+// without a mapping get a 500 "internal_error", and ok is false.
+func Lookup(err error) (status int, code string, ok bool) {
+	for _, m := range mappings {
+		if errors.Is(err, m.err) {
+			return m.status, m.code, true
+		}
+	}
+	return http.StatusInternalServerError, "internal_error", false
+}
+
+// Detail is the message of err clients may see: its own when it has a
+// mapping, a generic one otherwise, so internal details never reach them.
+func Detail(err error) string {
+	if _, _, ok := Lookup(err); ok {
+		return err.Error()
+	}
+	return "an unexpected error occurred"
+}
+
+// Error translates err into its problem using the known mappings. Errors
+// without a mapping are logged and answered with a generic 500.
+func Error(w http.ResponseWriter, r *http.Request, err error) {
+	status, code, ok := Lookup(err)
+	if !ok {
+		log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+	}
+	Write(w, r, status, code, Detail(err))
+}
+
+// NotFound and MethodNotAllowed replace the plain text responses of the router.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	Write(w, r, http.StatusNotFound, "route_not_found", "no route matches "+r.URL.Path)
+}
+
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	Write(w, r, http.StatusMethodNotAllowed, "method_not_allowed", r.Method+" is not allowed on "+r.URL.Path)
+}
@@ -0,0 +1,59 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sallescosta/user-and-products-manager/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func writeError(t *testing.T, err error) (*httptest.ResponseRecorder, Problem) {
+	req := httptest.NewRequest(http.MethodGet, "/products/1", nil)
+	rec := httptest.NewRecorder()
+	Error(rec, req, err)
+
+	var p Problem
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&p))
+	return rec, p
+}
+
+func TestError_Mapped(t *testing.T) {
+	rec, p := writeError(t, entity.ErrNameIsRequired)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+	assert.Equal(t, "name_required", p.Code)
+	assert.Equal(t, http.StatusBadRequest, p.Status)
+	assert.Equal(t, "name is required", p.Detail)
+	assert.Equal(t, "/products/1", p.Instance)
+
+	rec, p = writeError(t, fmt.Errorf("finding product: %w", gorm.ErrRecordNotFound))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "not_found", p.Code)
+}
+
+func TestError_Unmapped(t *testing.T) {
+	rec, p := writeError(t, errors.New("connection refused"))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, "internal_error", p.Code)
+	assert.NotContains(t, p.Detail, "connection refused")
+}
+
+func TestLookup(t *testing.T) {
+	status, code, ok := Lookup(fmt.Errorf("row 3: %w", entity.ErrInvalidPrice))
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "invalid_price", code)
+	assert.Equal(t, "row 3: invalid price", Detail(fmt.Errorf("row 3: %w", entity.ErrInvalidPrice)))
+
+	status, code, ok = Lookup(errors.New("disk full"))
+	assert.False(t, ok)
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, "internal_error", code)
+	assert.NotContains(t, Detail(errors.New("disk full")), "disk full")
+}
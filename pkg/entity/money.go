@@ -0,0 +1,193 @@
+package entity
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidCurrency  = errors.New("invalid currency")
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrInvalidPrecision = errors.New("too many decimal places for the currency")
+	ErrCurrencyMismatch = errors.New("currencies do not match")
+	ErrAmountOverflow   = errors.New("amount out of range")
+)
+
+// DefaultCurrency is used for amounts given without a currency and for the
+// prices stored before currencies existed.
+const DefaultCurrency = "USD"
+
+// currencies maps the supported ISO 4217 codes to their number of minor units.
+var currencies = map[string]int{
+	"AED": 2, "ARS": 2, "AUD": 2, "BHD": 3, "BRL": 2, "CAD": 2, "CHF": 2,
+	"CLP": 0, "CNY": 2, "COP": 2, "CZK": 2, "DKK": 2, "EGP": 2, "EUR": 2,
+	"GBP": 2, "HKD": 2, "HUF": 2, "IDR": 2, "ILS": 2, "INR": 2, "ISK": 0,
+	"JOD": 3, "JPY": 0, "KRW": 0, "KWD": 3, "MXN": 2, "MYR": 2, "NGN": 2,
+	"NOK": 2, "NZD": 2, "OMR": 3, "PEN": 2, "PHP": 2, "PLN": 2, "PYG": 0,
+	"RON": 2, "SAR": 2, "SEK": 2, "SGD": 2, "THB": 2, "TND": 3, "TRY": 2,
+	"TWD": 2, "UAH": 2, "USD": 2, "UYU": 2, "VND": 0, "ZAR": 2,
+}
+
+// MinorUnits returns the number of decimal places of currency.
+func MinorUnits(currency string) (int, error) {
+	units, ok := currencies[currency]
+	if !ok {
+		return 0, ErrInvalidCurrency
+	}
+	return units, nil
+}
+
+// Money is an exact amount, kept as an integer number of the minor units
+// (cents for USD) of an ISO 4217 currency.
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency" gorm:"size:3"`
+}
+
+// NewMoney returns amount minor units of currency.
+func NewMoney(amount int64, currency string) (Money, error) {
+	m := Money{Amount: amount, Currency: strings.ToUpper(currency)}
+	if err := m.Validate(); err != nil {
+		return Money{}, err
+	}
+	return m, nil
+}
+
+// ParseMoney reads a decimal amount such as "12.34" in currency. It never goes
+// through floating point, and refuses more decimal places than the currency
+// has.
+func ParseMoney(s, currency string) (Money, error) {
+	currency = strings.ToUpper(currency)
+	units, err := MinorUnits(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+
+	whole, fraction, _ := strings.Cut(s, ".")
+	if whole == "" && fraction == "" {
+		return Money{}, ErrInvalidAmount
+	}
+	if !digits(whole) || !digits(fraction) {
+		return Money{}, ErrInvalidAmount
+	}
+
+	fraction = strings.TrimRight(fraction, "0")
+	if len(fraction) > units {
+		return Money{}, ErrInvalidPrecision
+	}
+	fraction += strings.Repeat("0", units-len(fraction))
+
+	var amount int64
+	if whole+fraction != "" {
+		amount, err = strconv.ParseInt(whole+fraction, 10, 64)
+		if err != nil {
+			return Money{}, ErrAmountOverflow
+		}
+	}
+	if negative {
+		amount = -amount
+	}
+
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+func digits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate checks that the currency is a supported ISO 4217 code.
+func (m Money) Validate() error {
+	_, err := MinorUnits(m.Currency)
+	return err
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// Add returns m + o. Both must be in the same currency.
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	sum := m.Amount + o.Amount
+	if (o.Amount > 0 && sum < m.Amount) || (o.Amount < 0 && sum > m.Amount) {
+		return Money{}, ErrAmountOverflow
+	}
+	return Money{Amount: sum, Currency: m.Currency}, nil
+}
+
+// Sub returns m - o. Both must be in the same currency.
+func (m Money) Sub(o Money) (Money, error) {
+	if o.Amount == math.MinInt64 {
+		return Money{}, ErrAmountOverflow
+	}
+	return m.Add(Money{Amount: -o.Amount, Currency: o.Currency})
+}
+
+// Mul returns m multiplied by n, e.g. the total of n units at price m.
+func (m Money) Mul(n int64) (Money, error) {
+	if m.Amount != 0 && n != 0 {
+		product := m.Amount * n
+		if product/n != m.Amount || (m.Amount == -1 && n == math.MinInt64) || (n == -1 && m.Amount == math.MinInt64) {
+			return Money{}, ErrAmountOverflow
+		}
+		return Money{Amount: product, Currency: m.Currency}, nil
+	}
+	return Money{Currency: m.Currency}, nil
+}
+
+// Cmp compares m and o, returning -1, 0 or +1. Both must be in the same
+// currency.
+func (m Money) Cmp(o Money) (int, error) {
+	if m.Currency != o.Currency {
+		return 0, ErrCurrencyMismatch
+	}
+	switch {
+	case m.Amount < o.Amount:
+		return -1, nil
+	case m.Amount > o.Amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// Decimal formats the amount with the decimal places of its currency, as in
+// "12.34". Amounts in an unknown currency are formatted as minor units.
+func (m Money) Decimal() string {
+	units := currencies[m.Currency]
+
+	sign := ""
+	amount := strconv.FormatInt(m.Amount, 10)
+	if m.Amount < 0 {
+		sign, amount = "-", amount[1:]
+	}
+	if units == 0 {
+		return sign + amount
+	}
+
+	if len(amount) <= units {
+		amount = strings.Repeat("0", units-len(amount)+1) + amount
+	}
+	return sign + amount[:len(amount)-units] + "." + amount[len(amount)-units:]
+}
+
+// String formats the money as in "12.34 USD".
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
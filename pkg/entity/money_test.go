@@ -0,0 +1,96 @@
+package entity
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMoney(t *testing.T) {
+	cases := []struct {
+		in       string
+		currency string
+		amount   int64
+	}{
+		{"12.34", "USD", 1234},
+		{"12.3", "usd", 1230},
+		{"12", "USD", 1200},
+		{"0.1", "USD", 10},
+		{".5", "EUR", 50},
+		{"-1.05", "USD", -105},
+		{"12.340", "USD", 1234},
+		{"1500", "JPY", 1500},
+		{"1.000", "JPY", 1},
+		{"1.234", "KWD", 1234},
+	}
+
+	for _, c := range cases {
+		m, err := ParseMoney(c.in, c.currency)
+		assert.NoError(t, err, c.in)
+		assert.Equal(t, c.amount, m.Amount, c.in)
+	}
+}
+
+func TestParseMoney_Invalid(t *testing.T) {
+	_, err := ParseMoney("12.345", "USD")
+	assert.Equal(t, ErrInvalidPrecision, err)
+
+	_, err = ParseMoney("1.5", "JPY")
+	assert.Equal(t, ErrInvalidPrecision, err)
+
+	_, err = ParseMoney("1e3", "USD")
+	assert.Equal(t, ErrInvalidAmount, err)
+
+	_, err = ParseMoney(".", "USD")
+	assert.Equal(t, ErrInvalidAmount, err)
+
+	_, err = ParseMoney("10", "XYZ")
+	assert.Equal(t, ErrInvalidCurrency, err)
+
+	_, err = ParseMoney("99999999999999999999", "USD")
+	assert.Equal(t, ErrAmountOverflow, err)
+}
+
+func TestMoney_Arithmetic(t *testing.T) {
+	a, _ := NewMoney(1050, "USD")
+	b, _ := NewMoney(250, "USD")
+
+	sum, err := a.Add(b)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1300), sum.Amount)
+
+	diff, err := a.Sub(b)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(800), diff.Amount)
+
+	total, err := a.Mul(3)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3150), total.Amount)
+
+	cmp, err := a.Cmp(b)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, cmp)
+
+	_, err = a.Add(Money{Amount: 1, Currency: "EUR"})
+	assert.Equal(t, ErrCurrencyMismatch, err)
+
+	_, err = Money{Amount: math.MaxInt64, Currency: "USD"}.Add(b)
+	assert.Equal(t, ErrAmountOverflow, err)
+
+	_, err = Money{Amount: math.MaxInt64 / 2, Currency: "USD"}.Mul(3)
+	assert.Equal(t, ErrAmountOverflow, err)
+}
+
+func TestMoney_Format(t *testing.T) {
+	assert.Equal(t, "12.34 USD", Money{Amount: 1234, Currency: "USD"}.String())
+	assert.Equal(t, "0.05", Money{Amount: 5, Currency: "USD"}.Decimal())
+	assert.Equal(t, "-0.50", Money{Amount: -50, Currency: "EUR"}.Decimal())
+	assert.Equal(t, "1500", Money{Amount: 1500, Currency: "JPY"}.Decimal())
+	assert.Equal(t, "1.234", Money{Amount: 1234, Currency: "KWD"}.Decimal())
+}
+
+func TestNewMoney_InvalidCurrency(t *testing.T) {
+	_, err := NewMoney(100, "US")
+	assert.Equal(t, ErrInvalidCurrency, err)
+}
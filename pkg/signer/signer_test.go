@@ -0,0 +1,35 @@
+package signer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type payload struct {
+	ID string `json:"id"`
+	N  int    `json:"n"`
+}
+
+func TestSignAndVerify(t *testing.T) {
+	s := New("secret")
+
+	token, err := s.Sign(payload{ID: "a", N: 1})
+	assert.NoError(t, err)
+
+	var p payload
+	assert.NoError(t, s.Verify(token, &p))
+	assert.Equal(t, payload{ID: "a", N: 1}, p)
+}
+
+func TestVerify_Tampered(t *testing.T) {
+	s := New("secret")
+	token, _ := s.Sign(payload{ID: "a", N: 1})
+	forged, _ := New("other").Sign(payload{ID: "a", N: 2})
+
+	var p payload
+	assert.Equal(t, ErrInvalidSignature, s.Verify(forged, &p))
+	assert.Equal(t, ErrInvalidSignature, s.Verify(token[1:], &p))
+	assert.Equal(t, ErrInvalidSignature, s.Verify("garbage", &p))
+	assert.Equal(t, ErrInvalidSignature, s.Verify("", &p))
+}
@@ -0,0 +1,40 @@
+package thumbnail
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFit(t *testing.T) {
+	wide := Fit(image.NewRGBA(image.Rect(0, 0, 400, 200)), 100)
+	assert.Equal(t, image.Rect(0, 0, 100, 50), wide.Bounds())
+
+	tall := Fit(image.NewRGBA(image.Rect(0, 0, 30, 600)), 100)
+	assert.Equal(t, image.Rect(0, 0, 5, 100), tall.Bounds())
+
+	// a sliver keeps at least one pixel
+	sliver := Fit(image.NewRGBA(image.Rect(0, 0, 1000, 2)), 100)
+	assert.Equal(t, image.Rect(0, 0, 100, 1), sliver.Bounds())
+
+	small := image.NewRGBA(image.Rect(0, 0, 50, 20))
+	assert.Same(t, small, Fit(small, 100))
+}
+
+func TestEncode(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+
+	var buf bytes.Buffer
+	assert.NoError(t, Encode(&buf, img, "image/jpeg"))
+	_, err := jpeg.Decode(&buf)
+	assert.NoError(t, err)
+
+	buf.Reset()
+	assert.NoError(t, Encode(&buf, img, "image/gif"))
+	_, err = png.Decode(&buf)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,166 @@
+// Package xlsx writes spreadsheets in the Office Open XML format of Excel one
+// row at a time, straight to an io.Writer, so that large ones are never held
+// in memory. It writes a single sheet of plain text and number cells.
+package xlsx
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidSheetName = errors.New("sheet names must be 1 to 31 characters, without []:*?/\\")
+
+// Number is a cell holding a number, in decimal notation, such as "12.34".
+type Number string
+
+const (
+	mainNS = "http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+	relsNS = "http://schemas.openxmlformats.org/package/2006/relationships"
+	docNS  = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+)
+
+// parts are the files of the package besides the sheet, which is written last
+// as rows come.
+var parts = []struct{ name, content string }{
+	{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", `<Relationships xmlns="` + relsNS + `">` +
+		`<Relationship Id="rId1" Type="` + docNS + `/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/_rels/workbook.xml.rels", `<Relationships xmlns="` + relsNS + `">` +
+		`<Relationship Id="rId1" Type="` + docNS + `/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`<Relationship Id="rId2" Type="` + docNS + `/styles" Target="styles.xml"/>` +
+		`</Relationships>`},
+	{"xl/styles.xml", `<styleSheet xmlns="` + mainNS + `">` +
+		`<fonts count="1"><font><sz val="11"/><name val="Calibri"/></font></fonts>` +
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		`<cellXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/></cellXfs>` +
+		`</styleSheet>`},
+}
+
+type Writer struct {
+	zip   *zip.Writer
+	sheet *bufio.Writer
+	rows  int
+}
+
+// NewWriter starts a workbook with a single sheet of the given name.
+func NewWriter(w io.Writer, sheetName string) (*Writer, error) {
+	if sheetName == "" || len([]rune(sheetName)) > 31 || strings.ContainsAny(sheetName, `[]:*?/\`) {
+		return nil, ErrInvalidSheetName
+	}
+
+	archive := zip.NewWriter(w)
+	for _, part := range parts {
+		if err := writePart(archive, part.name, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	var name strings.Builder
+	_ = xml.EscapeText(&name, []byte(sheetName))
+	workbook := `<workbook xmlns="` + mainNS + `" xmlns:r="` + docNS + `">` +
+		`<sheets><sheet name="` + name.String() + `" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	if err := writePart(archive, "xl/workbook.xml", workbook); err != nil {
+		return nil, err
+	}
+
+	sheet, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	buffered := bufio.NewWriter(sheet)
+	if _, err = buffered.WriteString(xml.Header + `<worksheet xmlns="` + mainNS + `"><sheetData>`); err != nil {
+		return nil, err
+	}
+	return &Writer{zip: archive, sheet: buffered}, nil
+}
+
+func writePart(archive *zip.Writer, name, content string) error {
+	part, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(part, xml.Header+content)
+	return err
+}
+
+// WriteRow appends a row of cells, each a string, a Number, an int, an int64
+// or a float64.
+func (w *Writer) WriteRow(cells ...interface{}) error {
+	row := strconv.Itoa(w.rows + 1)
+
+	var b strings.Builder
+	b.WriteString(`<row r="` + row + `">`)
+	for i, cell := range cells {
+		ref := column(i) + row
+		switch v := cell.(type) {
+		case string:
+			b.WriteString(`<c r="` + ref + `" t="inlineStr"><is><t xml:space="preserve">`)
+			_ = xml.EscapeText(&b, []byte(v))
+			b.WriteString(`</t></is></c>`)
+		case Number:
+			if _, err := strconv.ParseFloat(string(v), 64); err != nil {
+				return fmt.Errorf("xlsx: %q is not a number", v)
+			}
+			b.WriteString(`<c r="` + ref + `"><v>` + string(v) + `</v></c>`)
+		case int:
+			b.WriteString(`<c r="` + ref + `"><v>` + strconv.Itoa(v) + `</v></c>`)
+		case int64:
+			b.WriteString(`<c r="` + ref + `"><v>` + strconv.FormatInt(v, 10) + `</v></c>`)
+		case float64:
+			b.WriteString(`<c r="` + ref + `"><v>` + strconv.FormatFloat(v, 'g', -1, 64) + `</v></c>`)
+		default:
+			return fmt.Errorf("xlsx: unsupported cell type %T", cell)
+		}
+	}
+	b.WriteString(`</row>`)
+
+	if _, err := w.sheet.WriteString(b.String()); err != nil {
+		return err
+	}
+	w.rows++
+	return nil
+}
+
+// Flush writes the rows buffered so far through to the underlying writer.
+func (w *Writer) Flush() error {
+	if err := w.sheet.Flush(); err != nil {
+		return err
+	}
+	return w.zip.Flush()
+}
+
+// Close ends the sheet and the workbook. It does not close the underlying
+// writer.
+func (w *Writer) Close() error {
+	if _, err := w.sheet.WriteString(`</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	if err := w.sheet.Flush(); err != nil {
+		return err
+	}
+	return w.zip.Close()
+}
+
+// column is the letter name of the column at index i: A, B, ..., Z, AA, ...
+func column(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
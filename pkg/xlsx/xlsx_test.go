@@ -0,0 +1,82 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type sheet struct {
+	Rows []struct {
+		R     string `xml:"r,attr"`
+		Cells []struct {
+			R      string `xml:"r,attr"`
+			T      string `xml:"t,attr"`
+			V      string `xml:"v"`
+			Inline string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, "Products")
+	assert.NoError(t, err)
+	assert.NoError(t, w.WriteRow("name", "price", "quantity"))
+	assert.NoError(t, w.WriteRow("Fish & <Chips>", Number("12.34"), 3))
+	assert.NoError(t, w.WriteRow("Plate", int64(7), 0.5))
+	assert.NoError(t, w.Close())
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+
+	files := map[string][]byte{}
+	for _, f := range archive.File {
+		r, err := f.Open()
+		assert.NoError(t, err)
+		files[f.Name], _ = io.ReadAll(r)
+		r.Close()
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml", "xl/worksheets/sheet1.xml"} {
+		assert.Contains(t, files, name)
+	}
+	assert.Contains(t, string(files["xl/workbook.xml"]), `name="Products"`)
+
+	var s sheet
+	assert.NoError(t, xml.Unmarshal(files["xl/worksheets/sheet1.xml"], &s))
+	assert.Len(t, s.Rows, 3)
+	assert.Equal(t, "2", s.Rows[1].R)
+	assert.Equal(t, "A2", s.Rows[1].Cells[0].R)
+	assert.Equal(t, "inlineStr", s.Rows[1].Cells[0].T)
+	assert.Equal(t, "Fish & <Chips>", s.Rows[1].Cells[0].Inline)
+	assert.Equal(t, "12.34", s.Rows[1].Cells[1].V)
+	assert.Equal(t, "3", s.Rows[1].Cells[2].V)
+	assert.Equal(t, "C3", s.Rows[2].Cells[2].R)
+	assert.Equal(t, "0.5", s.Rows[2].Cells[2].V)
+}
+
+func TestWriterErrors(t *testing.T) {
+	for _, name := range []string{"", "a/b", "[x]", "abcdefghijklmnopqrstuvwxyz0123456"} {
+		_, err := NewWriter(io.Discard, name)
+		assert.ErrorIs(t, err, ErrInvalidSheetName, name)
+	}
+
+	w, err := NewWriter(io.Discard, "Sheet")
+	assert.NoError(t, err)
+	assert.Error(t, w.WriteRow(true))
+	assert.Error(t, w.WriteRow(Number("1e")))
+}
+
+func TestColumn(t *testing.T) {
+	assert.Equal(t, "A", column(0))
+	assert.Equal(t, "Z", column(25))
+	assert.Equal(t, "AA", column(26))
+	assert.Equal(t, "AZ", column(51))
+	assert.Equal(t, "BA", column(52))
+	assert.Equal(t, "ZZ", column(701))
+	assert.Equal(t, "AAA", column(702))
+}